@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropic_option "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/rs/zerolog/log"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// vendors limit the length of custom ids, anthropic to 64 characters of [a-zA-Z0-9_-]
+const BATCH_CUSTOM_ID_MAX_LEN = 64
+
+var batchCustomIdRe = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// PromptBatch is a batch job submitted to a vendor, it is kept in the batch dir until its results are collected
+type PromptBatch struct {
+	Id     string
+	Vendor int
+	// model name as passed to prompt, with params
+	ModelName string
+	Key       string
+	Lang      string
+	CreatedAt time.Time
+	// nil until the results are written into problems
+	CollectedAt *time.Time `json:"CollectedAt,omitempty"`
+	Requests    []BatchRequest
+}
+
+type BatchRequest struct {
+	CustomId string
+	File     string
+	// solution fields known before the answer, like the prompt and the template
+	Solution Solution
+}
+
+// batchResult is an answer to a request of a batch in the form of a solution, or an error
+type batchResult struct {
+	solution *Solution
+	err      error
+}
+
+func vendorSupportsBatch(vendor int) bool {
+	return vendor == leetgptsolver.MODEL_VENDOR_OPENAI || vendor == leetgptsolver.MODEL_VENDOR_ANTHROPIC
+}
+
+// promptBatch renders prompts of all problems which are not solved yet and submits them as one batch job
+func promptBatch(files []string, lang, modelName, modelId string, params leetgptsolver.ModelParams, vendor int, withImages bool, shots []fewShot) {
+	if !vendorSupportsBatch(vendor) {
+		log.Error().Msgf("Batch prompting is not supported for model %s", modelId)
+		return
+	}
+	key := resultKey(modelName)
+
+	batch := PromptBatch{
+		Vendor:    vendor,
+		ModelName: modelName,
+		Key:       key,
+		Lang:      lang,
+	}
+	queued := map[string]string{}
+	if !options.Force {
+		var err error
+		queued, err = queuedBatchFiles(key, lang)
+		if err != nil {
+			log.Err(err).Msg("Failed to read pending batches")
+			return
+		}
+	}
+	prompts := []*ChatPrompt{}
+	skippedCnt := 0
+	errorsCnt := 0
+	for i, file := range files {
+		log.Info().Msgf("[%d/%d] Preparing prompt for problem %s ...", i+1, len(files), file)
+		if batchId, ok := queued[filepath.Clean(file)]; ok {
+			skippedCnt += 1
+			log.Info().Msgf("Already queued in batch %s which is not collected yet", batchId)
+			continue
+		}
+		problem, chatPrompt, err := preparePrompt(file, key, lang, withImages, shots)
+		if errors.Is(err, errAlreadySolved) {
+			skippedCnt += 1
+			log.Info().Msg(err.Error())
+			continue
+		}
+		if err != nil {
+			errorsCnt += 1
+			if errors.Is(err, ErrFatal) {
+				log.Error().Err(err).Msg("Failed to make prompt. Aborting...")
+				return
+			}
+			log.Err(err).Msgf("Skipping problem %s", file)
+			continue
+		}
+
+		solution := Solution{
+			Lang:         lang,
+			Prompt:       chatPrompt.Text,
+			PromptImages: len(chatPrompt.Images),
+			Model:        modelId,
+		}
+		setPromptFields(&solution, problem, chatPrompt)
+		customId := fmt.Sprintf("%d-%s", i, batchCustomIdRe.ReplaceAllString(problem.Question.Data.Question.TitleSlug, "_"))
+		if len(customId) > BATCH_CUSTOM_ID_MAX_LEN {
+			customId = customId[:BATCH_CUSTOM_ID_MAX_LEN]
+		}
+		batch.Requests = append(batch.Requests, BatchRequest{CustomId: customId, File: file, Solution: solution})
+		prompts = append(prompts, chatPrompt)
+	}
+	log.Info().Msgf("Files processed: %d", len(files))
+	log.Info().Msgf("Skipped problems: %d", skippedCnt)
+	log.Info().Msgf("Errors: %d", errorsCnt)
+	if len(batch.Requests) == 0 {
+		log.Info().Msg("No prompts to submit")
+		return
+	}
+	if options.DryRun {
+		log.Info().Msgf("Dry run: batch of %d prompts is not submitted", len(batch.Requests))
+		return
+	}
+
+	var err error
+	switch vendor {
+	case leetgptsolver.MODEL_VENDOR_OPENAI:
+		batch.Id, err = submitOpenAiBatch(batch, prompts, modelId, params)
+	case leetgptsolver.MODEL_VENDOR_ANTHROPIC:
+		batch.Id, err = submitAnthropicBatch(batch, prompts, modelId, params)
+	}
+	if err != nil {
+		log.Err(err).Msg("Failed to submit the batch")
+		return
+	}
+	batch.CreatedAt = time.Now()
+	path, err := saveBatch(batch)
+	if err != nil {
+		// the batch runs anyway, its id is needed to collect the results
+		log.Err(err).Msgf("Failed to save batch %s", batch.Id)
+		return
+	}
+	log.Info().Msgf("Submitted batch %s of %d prompts, saved to %s. Run prompt --collect to get the results", batch.Id, len(batch.Requests), path)
+}
+
+func submitOpenAiBatch(batch PromptBatch, prompts []*ChatPrompt, modelId string, params leetgptsolver.ModelParams) (string, error) {
+	client := openai.NewClient(options.ChatgptApiKey)
+	request := openai.CreateBatchWithUploadFileRequest{
+		Endpoint:         openai.BatchEndpointChatCompletions,
+		CompletionWindow: "24h",
+	}
+	for i, r := range batch.Requests {
+		request.AddChatCompletion(r.CustomId, openAiRequest(prompts[i], modelId, params))
+	}
+	resp, err := client.CreateBatchWithUploadFile(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func submitAnthropicBatch(batch PromptBatch, prompts []*ChatPrompt, modelId string, modelParams leetgptsolver.ModelParams) (string, error) {
+	client := anthropic.NewClient(anthropic_option.WithAPIKey(options.ClaudeApiKey))
+	requests := []anthropic.MessageBatchNewParamsRequest{}
+	for i, r := range batch.Requests {
+		params := anthropicParams(prompts[i], modelId, modelParams)
+		requests = append(requests, anthropic.MessageBatchNewParamsRequest{
+			CustomID: r.CustomId,
+			Params: anthropic.MessageBatchNewParamsRequestParams{
+				Model:       params.Model,
+				MaxTokens:   params.MaxTokens,
+				Messages:    params.Messages,
+				System:      params.System,
+				Temperature: params.Temperature,
+				TopP:        params.TopP,
+				Thinking:    params.Thinking,
+			},
+		})
+	}
+	resp, err := client.Messages.Batches.New(context.Background(), anthropic.MessageBatchNewParams{Requests: requests})
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// collectBatches polls batches until they end and writes their results into problems.
+// Batches are taken from args or, without args, from the batch dir
+func collectBatches(args []string) {
+	paths, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+	if len(paths) == 0 {
+		paths, err = filepath.Glob(filepath.Join(options.BatchDir, "*.json"))
+		if err != nil {
+			log.Err(err).Msg("Failed to list batches")
+			return
+		}
+	}
+
+	pending := []string{}
+	total := 0
+	for _, path := range paths {
+		batch, err := readBatch(path)
+		if err != nil {
+			log.Err(err).Msgf("Failed to read batch %s", path)
+			continue
+		}
+		if batch.CollectedAt != nil && !options.Force {
+			log.Debug().Msgf("Batch %s is already collected", batch.Id)
+			continue
+		}
+		pending = append(pending, path)
+		total += len(batch.Requests)
+	}
+	log.Info().Msgf("Collecting %d batches...", len(pending))
+	// requests of batches which are still running at the end are not done
+	tracker := newProgress("collect", total)
+	journal := openJournal("collect")
+
+	for len(pending) > 0 {
+		running := []string{}
+		for _, path := range pending {
+			done, err := collectBatch(path, tracker, journal)
+			if err != nil {
+				log.Err(err).Msgf("Failed to collect batch %s", path)
+				continue
+			}
+			if !done {
+				running = append(running, path)
+			}
+		}
+		pending = running
+		if len(pending) == 0 || options.BatchPollInterval <= 0 {
+			break
+		}
+		log.Info().Msgf("%d batches are still running, next check in %s", len(pending), options.BatchPollInterval)
+		time.Sleep(options.BatchPollInterval)
+	}
+	if len(pending) > 0 {
+		log.Info().Msgf("%d batches are still running", len(pending))
+	}
+	summary := tracker.summary()
+	tracker.finish()
+	journal.close(summary)
+}
+
+// collectBatch writes results of the batch into problems if the batch has ended
+func collectBatch(path string, tracker *progress, journal *journal) (bool, error) {
+	batch, err := readBatch(path)
+	if err != nil {
+		return false, err
+	}
+
+	var results map[string]batchResult
+	switch batch.Vendor {
+	case leetgptsolver.MODEL_VENDOR_OPENAI:
+		results, err = openAiBatchResults(batch)
+	case leetgptsolver.MODEL_VENDOR_ANTHROPIC:
+		results, err = anthropicBatchResults(batch)
+	default:
+		err = fmt.Errorf("unsupported batch vendor %d", batch.Vendor)
+	}
+	if err != nil || results == nil {
+		return false, err
+	}
+	return true, storeBatchResults(batch, results, tracker, journal)
+}
+
+// storeBatchResults writes results of the ended batch into problems and marks the batch collected.
+// Requests are counted and journaled like synchronous prompts
+func storeBatchResults(batch PromptBatch, results map[string]batchResult, tracker *progress, journal *journal) error {
+	solvedCnt := 0
+	errorsCnt := 0
+	for i, r := range batch.Requests {
+		log.Info().Msgf("[%d/%d] Collecting %s for problem %s ...", i+1, len(batch.Requests), batch.ModelName, r.File)
+		done := func(outcome string, solution *Solution, err error) {
+			if err != nil {
+				errorsCnt += 1
+			}
+			tracker.add(batch.ModelName, outcome)
+			event := JournalEvent{Event: JOURNAL_PROMPT, Problem: r.File, Model: batch.ModelName, Lang: batch.Lang, Status: outcome}
+			journal.record(errorEvent(solutionEvent(event, solution), err))
+		}
+		result, ok := results[r.CustomId]
+		if !ok {
+			done(OUTCOME_ERROR, nil, fmt.Errorf("no result for request %s", r.CustomId))
+			log.Error().Msgf("No result for request %s", r.CustomId)
+			continue
+		}
+		if result.err != nil {
+			done(OUTCOME_ERROR, nil, result.err)
+			log.Err(result.err).Msgf("Request %s failed", r.CustomId)
+			continue
+		}
+
+		var problem Problem
+		err := problem.ReadProblem(r.File)
+		if err != nil {
+			done(OUTCOME_ERROR, nil, err)
+			log.Err(err).Msg("Failed to read the problem")
+			continue
+		}
+		solution := r.Solution
+		solution.Answer = result.solution.Answer
+		if result.solution.Model != "" {
+			solution.Model = result.solution.Model
+		}
+		solution.PromptTokens = result.solution.PromptTokens
+		solution.OutputTokens = result.solution.OutputTokens
+		solution.SolvedAt = time.Now()
+		solution.BatchId = batch.Id
+		storeSolution(&problem, batch.Key, &solution)
+		log.Info().Msgf("Got %d line(s) of code", strings.Count(solution.TypedCode, "\n"))
+		if !options.DryRun {
+			err = problem.SaveProblemInto(r.File)
+			if err != nil {
+				done(OUTCOME_ERROR, &solution, err)
+				log.Err(err).Msg("Failed to save the solution")
+				continue
+			}
+		}
+		solvedCnt += 1
+		done(OUTCOME_SOLVED, &solution, nil)
+	}
+	log.Info().Msgf("Batch %s: problems solved successfully: %d, errors: %d", batch.Id, solvedCnt, errorsCnt)
+
+	if options.DryRun {
+		return nil
+	}
+	collectedAt := time.Now()
+	batch.CollectedAt = &collectedAt
+	_, err := saveBatch(batch)
+	return err
+}
+
+// failedBatchResults fails every request of a batch which failed as a whole, so the batch is collected
+// and its problems can be prompted again
+func failedBatchResults(batch PromptBatch, err error) map[string]batchResult {
+	results := map[string]batchResult{}
+	for _, r := range batch.Requests {
+		results[r.CustomId] = batchResult{err: err}
+	}
+	return results
+}
+
+// openAiBatchResults returns nil results while the batch is running
+func openAiBatchResults(batch PromptBatch) (map[string]batchResult, error) {
+	client := openai.NewClient(options.ChatgptApiKey)
+	ctx := context.Background()
+	resp, err := client.RetrieveBatch(ctx, batch.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve batch: %w", err)
+	}
+	switch resp.Status {
+	case "validating", "in_progress", "finalizing", "cancelling":
+		log.Info().Msgf("Batch %s is %s: %d/%d completed, %d failed", batch.Id, resp.Status, resp.RequestCounts.Completed, resp.RequestCounts.Total, resp.RequestCounts.Failed)
+		return nil, nil
+	case "failed":
+		reasons := []string{}
+		if resp.Errors != nil {
+			for _, e := range resp.Errors.Data {
+				reasons = append(reasons, e.Code+": "+e.Message)
+			}
+		}
+		log.Warn().Msgf("Batch %s failed: %s", batch.Id, strings.Join(reasons, "; "))
+		return failedBatchResults(batch, fmt.Errorf("batch failed: %s", strings.Join(reasons, "; "))), nil
+	}
+	// completed, expired and cancelled batches have results of the finished requests
+	results := map[string]batchResult{}
+	for _, fileId := range []*string{resp.OutputFileID, resp.ErrorFileID} {
+		if fileId == nil || *fileId == "" {
+			continue
+		}
+		content, err := client.GetFileContent(ctx, *fileId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch results: %w", err)
+		}
+		err = readOpenAiBatchOutput(content, results)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func readOpenAiBatchOutput(content openai.RawResponse, results map[string]batchResult) error {
+	scanner := bufio.NewScanner(content)
+	// answers are long
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var line struct {
+			CustomId string `json:"custom_id"`
+			Response *struct {
+				StatusCode int                           `json:"status_code"`
+				Body       openai.ChatCompletionResponse `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			return fmt.Errorf("failed to parse batch output: %w", err)
+		}
+		switch {
+		case line.Error != nil:
+			results[line.CustomId] = batchResult{err: fmt.Errorf("%s: %s", line.Error.Code, line.Error.Message)}
+		case line.Response == nil || line.Response.StatusCode != 200:
+			results[line.CustomId] = batchResult{err: errors.New("request failed")}
+		case len(line.Response.Body.Choices) == 0:
+			results[line.CustomId] = batchResult{err: errors.New("no choices in response")}
+		default:
+			body := line.Response.Body
+			results[line.CustomId] = batchResult{solution: &Solution{
+				Answer:       body.Choices[0].Message.Content,
+				Model:        body.Model,
+				PromptTokens: body.Usage.PromptTokens,
+				OutputTokens: body.Usage.CompletionTokens,
+			}}
+		}
+	}
+	return scanner.Err()
+}
+
+// anthropicBatchResults returns nil results while the batch is running
+func anthropicBatchResults(batch PromptBatch) (map[string]batchResult, error) {
+	client := anthropic.NewClient(anthropic_option.WithAPIKey(options.ClaudeApiKey))
+	ctx := context.Background()
+	resp, err := client.Messages.Batches.Get(ctx, batch.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve batch: %w", err)
+	}
+	if resp.ProcessingStatus != anthropic.MessageBatchProcessingStatusEnded {
+		counts := resp.RequestCounts
+		log.Info().Msgf("Batch %s is %s: %d processing, %d succeeded, %d errored", batch.Id, resp.ProcessingStatus, counts.Processing, counts.Succeeded, counts.Errored)
+		return nil, nil
+	}
+
+	results := map[string]batchResult{}
+	stream := client.Messages.Batches.ResultsStreaming(ctx, batch.Id)
+	defer stream.Close()
+	for stream.Next() {
+		r := stream.Current()
+		if r.Result.Type != "succeeded" {
+			results[r.CustomID] = batchResult{err: fmt.Errorf("request %s: %s", r.Result.Type, r.Result.Error.Error.Message)}
+			continue
+		}
+		results[r.CustomID] = batchResult{solution: &Solution{
+			Answer:       anthropicAnswer(&r.Result.Message),
+			Model:        string(r.Result.Message.Model),
+			PromptTokens: int(r.Result.Message.Usage.InputTokens),
+			OutputTokens: int(r.Result.Message.Usage.OutputTokens),
+		}}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+	return results, nil
+}
+
+// queuedBatchFiles returns files of problems in batches of the key and language which are not collected yet,
+// with ids of the batches
+func queuedBatchFiles(key, lang string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(options.BatchDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	queued := map[string]string{}
+	for _, path := range paths {
+		batch, err := readBatch(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch %s: %w", path, err)
+		}
+		if batch.CollectedAt != nil || batch.Key != key || batch.Lang != lang {
+			continue
+		}
+		for _, r := range batch.Requests {
+			queued[filepath.Clean(r.File)] = batch.Id
+		}
+	}
+	return queued, nil
+}
+
+func saveBatch(batch PromptBatch) (string, error) {
+	err := os.MkdirAll(options.BatchDir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create batch dir: %w", err)
+	}
+	path := filepath.Join(options.BatchDir, batch.Id+".json")
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write batch: %w", err)
+	}
+	return path, nil
+}
+
+func readBatch(path string) (PromptBatch, error) {
+	var batch PromptBatch
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return batch, err
+	}
+	err = json.Unmarshal(data, &batch)
+	if err != nil {
+		return batch, fmt.Errorf("failed to unmarshal batch: %w", err)
+	}
+	return batch, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailedBatchIsNotQueued(t *testing.T) {
+	batchDir, dryRun := options.BatchDir, options.DryRun
+	t.Cleanup(func() { options.BatchDir, options.DryRun = batchDir, dryRun })
+	options.BatchDir = t.TempDir()
+	options.DryRun = false
+
+	batch := PromptBatch{
+		Id:   "batch_1",
+		Key:  "gpt-4o",
+		Lang: "python3",
+		Requests: []BatchRequest{
+			{CustomId: "0-two_sum", File: "problems/two-sum.json"},
+			{CustomId: "1-add_two_numbers", File: "problems/add-two-numbers.json"},
+		},
+	}
+	path, err := saveBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		lang     string
+		expected int
+	}{
+		{name: "same key and language", key: "gpt-4o", lang: "python3", expected: 2},
+		{name: "another language", key: "gpt-4o", lang: "cpp", expected: 0},
+		{name: "another key", key: "o3", lang: "python3", expected: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queued, err := queuedBatchFiles(test.key, test.lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queued) != test.expected {
+				t.Errorf("expected %d queued files, got: %v", test.expected, queued)
+			}
+		})
+	}
+
+	tracker := newProgress("collect", len(batch.Requests))
+	err = storeBatchResults(batch, failedBatchResults(batch, errors.New("batch failed")), tracker, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := tracker.summary(); summary.Counts[OUTCOME_ERROR] != 2 {
+		t.Errorf("expected 2 errors in the summary, got: %v", summary.Counts)
+	}
+	collected, err := readBatch(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collected.CollectedAt == nil {
+		t.Error("failed batch is not marked collected")
+	}
+	queued, err := queuedBatchFiles("gpt-4o", "python3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := queued[filepath.Clean("problems/two-sum.json")]; ok || len(queued) != 0 {
+		t.Errorf("problems of the failed batch are still queued: %v", queued)
+	}
+}
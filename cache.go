@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// responseCache keeps answers of models on disk, keyed by everything which makes the request
+type responseCache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cacheKeyData is hashed into the cache key, changing it invalidates the whole cache
+type cacheKeyData struct {
+	Vendor  int
+	ModelId string
+	// canonical json as returned by ParseModelName
+	Params string
+	Seed   int
+	System string
+	Shots  []ChatShot
+	Text   string
+	// hashes of image data, urls may change
+	Images []string
+}
+
+func newResponseCache(dir string) *responseCache {
+	return &responseCache{dir: dir}
+}
+
+func (c *responseCache) key(p *ChatPrompt, vendor int, modelId, params string) string {
+	data := cacheKeyData{
+		Vendor:  vendor,
+		ModelId: modelId,
+		Params:  params,
+		Seed:    PROMPT_SEED,
+		System:  p.System,
+		Shots:   p.Shots,
+		Text:    p.Text,
+	}
+	for _, image := range p.Images {
+		sum := sha256.Sum256(image.Data)
+		data.Images = append(data.Images, image.MimeType+":"+hex.EncodeToString(sum[:]))
+	}
+	b, _ := json.Marshal(data)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *responseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get returns a copy of the cached solution, counting hits and misses
+func (c *responseCache) get(key string) (*Solution, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Err(err).Msg("Failed to read the response cache")
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+	var solution Solution
+	err = json.Unmarshal(data, &solution)
+	if err != nil {
+		log.Err(err).Msgf("Invalid response cache entry %s", key)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &solution, true
+}
+
+func (c *responseCache) put(key string, solution *Solution) error {
+	path := c.path(key)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	data, err := json.Marshal(solution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the solution: %w", err)
+	}
+	// written via a temp file, so parallel workers never read a partial entry
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/rs/zerolog/log"
+)
+
+const LOCAL_CHECK_TIMEOUT = 2 * time.Minute
+
+// check output is truncated to keep problem files small
+const LOCAL_CHECK_MAX_OUTPUT = 4096
+
+// LocalCheck is the result of a local syntax or compile check of the solution
+type LocalCheck struct {
+	Tool      string
+	Passed    bool
+	Output    string `json:"Output,omitempty"`
+	CheckedAt time.Time
+}
+
+// localChecker checks code of a language with a local toolchain
+type localChecker struct {
+	// binary looked up in PATH
+	tool string
+	file string
+	// added before definitions and the code
+	prelude string
+	// definitions go before the code where declaration order matters (c, cpp, python),
+	// otherwise after the code, so imports of the code stay at the top
+	definitionsFirst bool
+	args             func(dir, file string) []string
+}
+
+var localCheckers = map[string]localChecker{
+	"python3": {
+		tool:             "python3",
+		file:             "solution.py",
+		prelude:          "from typing import *\n",
+		definitionsFirst: true,
+		args:             func(dir, file string) []string { return []string{"-m", "py_compile", file} },
+	},
+	"golang": {
+		tool: "go",
+		file: "solution.go",
+		// not main: go vet requires func main() in the main package
+		prelude: "package solution\n\n",
+		args:    func(dir, file string) []string { return []string{"vet", "."} },
+	},
+	"c": {
+		tool:             "gcc",
+		file:             "solution.c",
+		prelude:          "#include <stdbool.h>\n#include <stdio.h>\n#include <stdlib.h>\n#include <string.h>\n#include <limits.h>\n#include <math.h>\n",
+		definitionsFirst: true,
+		args:             func(dir, file string) []string { return []string{"-fsyntax-only", "-std=gnu11", file} },
+	},
+	"cpp": {
+		tool:             "g++",
+		file:             "solution.cpp",
+		prelude:          "#include <bits/stdc++.h>\nusing namespace std;\n",
+		definitionsFirst: true,
+		args:             func(dir, file string) []string { return []string{"-fsyntax-only", "-std=c++20", file} },
+	},
+	"java": {
+		tool:    "javac",
+		file:    "Solution.java",
+		prelude: "import java.util.*;\nimport java.util.function.*;\nimport java.util.stream.*;\nimport java.math.*;\n",
+		args: func(dir, file string) []string {
+			return []string{"-d", filepath.Join(dir, "out"), "-nowarn", file}
+		},
+	},
+	"rust": {
+		tool: "rustc",
+		file: "solution.rs",
+		// glob imports do not conflict with imports of the code
+		prelude: "#![allow(dead_code, unused_imports)]\nuse std::collections::*;\nuse std::rc::*;\nuse std::cell::*;\npub struct Solution;\n",
+		args: func(dir, file string) []string {
+			return []string{"--edition=2021", "--crate-type=lib", "--emit=metadata", "-o", filepath.Join(dir, "solution.rmeta"), file}
+		},
+	},
+}
+
+// leetcode adds missing imports to go code, so without goimports go vet reports false errors
+// and only the syntax is checked
+var goSyntaxChecker = localChecker{
+	tool:    "gofmt",
+	file:    "solution.go",
+	prelude: "package solution\n\n",
+	args:    func(dir, file string) []string { return []string{"-e", "-l", file} },
+}
+
+func check(args []string, lang, modelName string) {
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+	checker, ok := localCheckers[lang]
+	if !ok {
+		log.Error().Msgf("No local checker for %s", lang)
+		return
+	}
+	if lang == "golang" {
+		if _, err := exec.LookPath("goimports"); err != nil {
+			log.Warn().Msg("goimports is not available, checking only the syntax of go code")
+			checker = goSyntaxChecker
+		}
+	}
+	if _, err := exec.LookPath(checker.tool); err != nil {
+		log.Err(err).Msgf("%s is not available, cannot check %s solutions", checker.tool, lang)
+		return
+	}
+
+	key := resultKey(modelName)
+	log.Info().Msgf("Checking %d solutions with %s...", len(files), checker.tool)
+	passedCnt := 0
+	failedCnt := 0
+	skippedCnt := 0
+	errorsCnt := 0
+	for i, file := range files {
+		log.Info().Msgf("[%d/%d] Checking problem %s ...", i+1, len(files), file)
+
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			log.Err(err).Msg("Failed to read the problem")
+			errorsCnt += 1
+			continue
+		}
+		solution, ok := problem.GetSolution(key, lang)
+		if !ok || solution.TypedCode == "" {
+			log.Warn().Msgf("Model %s has no solution in %s to check", key, lang)
+			skippedCnt += 1
+			continue
+		}
+		if solution.LocalCheck != nil && !options.Force {
+			log.Info().Msgf("Already checked at %s", solution.LocalCheck.CheckedAt.String())
+			skippedCnt += 1
+			continue
+		}
+
+		localCheck, err := checker.check(problem.Question, solution)
+		if err != nil {
+			log.Err(err).Msg("Failed to check the solution")
+			errorsCnt += 1
+			continue
+		}
+		if localCheck.Passed {
+			log.Info().Msg("Check passed")
+			passedCnt += 1
+		} else {
+			log.Warn().Msgf("Check failed:\n%s", localCheck.Output)
+			failedCnt += 1
+		}
+
+		solution.LocalCheck = localCheck
+		problem.setSolution(key, lang, solution)
+		if !options.DryRun {
+			err = problem.SaveProblemInto(file)
+			if err != nil {
+				log.Err(err).Msg("Failed to save the check result")
+				errorsCnt += 1
+				continue
+			}
+		}
+	}
+	log.Info().Msgf("Files processed: %d", len(files))
+	log.Info().Msgf("Skipped problems: %d", skippedCnt)
+	log.Info().Msgf("Solutions checked: %d (passed: %d, failed: %d)", passedCnt+failedCnt, passedCnt, failedCnt)
+	log.Info().Msgf("Errors: %d", errorsCnt)
+}
+
+// check compiles the code which would be submitted, with definitions uncommented from the snippet
+func (c localChecker) check(q Question, s Solution) (*LocalCheck, error) {
+	code, _, err := codeToSubmit(q, s, CODE_VARIANT_PLAIN)
+	if err != nil {
+		return nil, err
+	}
+	definitions := leetgptsolver.UncommentDefinitions(q.FindSnippet(s.Lang))
+	if s.Lang == "java" {
+		// the file is named after Solution, so other classes cannot be public
+		definitions = strings.ReplaceAll(definitions, "public class ", "class ")
+	}
+	source := c.prelude
+	if c.definitionsFirst {
+		source += definitions + "\n" + code
+	} else {
+		source += code + "\n" + definitions
+	}
+
+	dir, err := os.MkdirTemp("", "leetgptsolver-check-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, c.file)
+	err = os.WriteFile(file, []byte(source), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the source: %w", err)
+	}
+	if c.tool == "go" {
+		err = prepareGoModule(dir, file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LOCAL_CHECK_TIMEOUT)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.tool, c.args(dir, file)...)
+	cmd.Dir = dir
+	log.Trace().Msgf("Running %s with source:\n%s", cmd.String(), source)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%s timed out: %w", c.tool, ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run %s: %w", c.tool, err)
+	}
+
+	// temp paths are different on every run and meaningless in reports
+	out := strings.ReplaceAll(string(output), dir+string(filepath.Separator), "")
+	if len(out) > LOCAL_CHECK_MAX_OUTPUT {
+		out = out[:LOCAL_CHECK_MAX_OUTPUT] + "..."
+	}
+	return &LocalCheck{
+		Tool:      c.tool,
+		Passed:    err == nil,
+		Output:    out,
+		CheckedAt: time.Now(),
+	}, nil
+}
+
+// prepareGoModule makes the dir a module, so go vet can run, and adds missing imports like leetcode does.
+// goimports also removes unused imports which leetcode rejects, so only imports it added are taken
+// and the code is checked as submitted otherwise
+func prepareGoModule(dir, file string) error {
+	err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module solution\n\ngo 1.21\n"), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write go.mod: %w", err)
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read the source: %w", err)
+	}
+	output, err := exec.Command("goimports", file).Output()
+	if err != nil {
+		// syntax errors are reported by go vet
+		log.Debug().Err(err).Msg("goimports failed")
+		return nil
+	}
+	imports, err := goImportPaths(source)
+	if err != nil {
+		return nil
+	}
+	fixed, err := goImportPaths(output)
+	if err != nil {
+		return nil
+	}
+	added := []string{}
+	for _, path := range fixed {
+		if !slices.Contains(imports, path) {
+			added = append(added, path)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	log.Debug().Msgf("Adding missing imports: %v", added)
+	// a single line right after the package clause of the prelude, so line numbers shift by one only
+	pkg, code, _ := strings.Cut(string(source), "\n")
+	code = pkg + "\nimport (" + strings.Join(added, "; ") + ")\n" + code
+	err = os.WriteFile(file, []byte(code), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write the source: %w", err)
+	}
+	return nil
+}
+
+// goImportPaths returns quoted paths of imports in go source
+func goImportPaths(source []byte) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", source, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, spec := range f.Imports {
+		paths = append(paths, spec.Path.Value)
+	}
+	return paths, nil
+}
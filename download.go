@@ -8,9 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path"
-	"path/filepath"
 	"slices"
-	"strings"
 	"syscall"
 	"time"
 
@@ -62,9 +60,7 @@ func download(category string, args []string) {
 
 		log.Info().Msgf("searching for %d question(s)...", len(files))
 		for _, file := range files {
-			// TODO: very awkward, improve
-			title := filepath.Base(file)
-			title = strings.TrimSuffix(title, filepath.Ext(file))
+			title := leetcodeSite.SlugFromFilename(file)
 
 			if qs, ok := slugsMap[title]; ok {
 				slugsToDownload = append(slugsToDownload, qs)
@@ -76,13 +72,22 @@ func download(category string, args []string) {
 	downloadQuestions(slugsToDownload)
 }
 
+// categories listed by the problems api
+var apiCategories = []string{"all", "algorithms", "database", "shell", "concurrency"}
+
+// categories available only via graphql
+var graphqlCategories = []string{"pandas", "javascript"}
+
 func getAvailableSlugs(category string) ([]QuestionSlug, error) {
-	if !slices.Contains([]string{"all", "algorithms", "database"}, category) {
+	if slices.Contains(graphqlCategories, category) {
+		return LoadProblemsetQuestionSlugs(category)
+	}
+	if !slices.Contains(apiCategories, category) {
 		return nil, fmt.Errorf("unsupported category: %s", category)
 	}
 
 	c := client()
-	resp, err := c.Get("https://leetcode.com/api/problems/" + category + "/")
+	resp, err := c.Get(leetcodeSite.ProblemsApiUrl(category))
 	if err != nil {
 		return nil, err
 	}
@@ -110,8 +115,13 @@ func printQuestions(slugs []QuestionSlug) {
 }
 
 func makeQuestionQuery(q QuestionSlug) ([]byte, error) {
+	// fields which differ between sites
+	siteFields := "companyTagStats"
+	if leetcodeSite.HasTranslations {
+		siteFields = "translatedTitle\n\t\t\t\ttranslatedContent"
+	}
 	query := map[string]interface{}{
-		"query": `query questionContent($titleSlug: String!)
+		"query": fmt.Sprintf(`query questionContent($titleSlug: String!)
 		{
 			question(titleSlug: $titleSlug) {
 				questionId
@@ -140,9 +150,9 @@ func makeQuestionQuery(q QuestionSlug) ([]byte, error) {
 					langSlug
 					code
 				}
-				companyTagStats
+				%s
 			}
-		}`,
+		}`, siteFields),
 		"variables": map[string]string{
 			"titleSlug": q.Stat.TitleSlug,
 		},
@@ -168,7 +178,7 @@ func downloadQuestions(slugs []QuestionSlug) int {
 		}
 		log.Debug().Msgf("leetcode username: %s", userStatus.Username)
 		if userStatus.Username == "" {
-			log.Fatal().Msg("leetcode username is empty. Please ensure you are signed in Firefox before downloading questions, or use -A to allow anonymous download.")
+			log.Fatal().Msg("leetcode username is empty. Please ensure you are signed in (see \"login status\") before downloading questions, or use -A to allow anonymous download.")
 			return -1
 		}
 	}
@@ -182,6 +192,7 @@ func downloadQuestions(slugs []QuestionSlug) int {
 	errorsCnt := 0
 	consecutiveErrorsCnt := 0
 	var exitSignal os.Signal = nil
+	tracker := newProgress("download", len(slugs))
 
 	c := colly.NewCollector(
 		colly.Async(true),
@@ -190,10 +201,10 @@ func downloadQuestions(slugs []QuestionSlug) int {
 	if !options.SkipPaid {
 		c.SetCookieJar(cookieJar())
 	}
+	// request rate is controlled by the shared leetcode limiter in the transport
 	err = c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
 		Parallelism: 2,
-		RandomDelay: 15 * time.Second,
 	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to set download limits")
@@ -208,6 +219,8 @@ func downloadQuestions(slugs []QuestionSlug) int {
 
 	c.OnResponse(func(r *colly.Response) {
 		consecutiveErrorsCnt = 0
+		outcome := OUTCOME_ERROR
+		defer func() { tracker.add("", outcome) }()
 		if exitSignal != nil {
 			log.Info().Msg("terminated by user")
 			// we don't like os.Exit, but it seems that colly doesn't have a good way to stop parallel requests
@@ -225,6 +238,12 @@ func downloadQuestions(slugs []QuestionSlug) int {
 		}
 		problem.DownloadedAt = time.Now()
 		problem.Question.DownloadedAt = problem.DownloadedAt
+		if leetcodeSite.Name != DEFAULT_SITE {
+			problem.Site = leetcodeSite.Name
+		}
+		if translated := problem.Question.Data.Question.TranslatedContent; translated != "" {
+			problem.Translations = map[string]string{CN_CONTENT_LANG: translated}
+		}
 
 		dstFile := r.Ctx.Get("dstFile")
 		if dstFile == "" {
@@ -242,7 +261,19 @@ func downloadQuestions(slugs []QuestionSlug) int {
 			return
 		}
 
-		if options.DetectApproxCreationDate {
+		if options.DownloadImages {
+			images, err := downloadQuestionImages(problem.Question)
+			if err != nil {
+				log.Err(err).Msgf("failed to download images for %s", problem.Question.Data.Question.TitleSlug)
+			} else if len(images) > 0 {
+				log.Info().Msgf("downloaded %d image(s) for %s", len(images), problem.Question.Data.Question.TitleSlug)
+				problem.Question.Images = images
+			}
+		}
+
+		if options.DetectApproxCreationDate && !leetcodeSite.HasUgcApi {
+			log.Debug().Msgf("approximate creation date detection is not supported for %s", leetcodeSite.Name)
+		} else if options.DetectApproxCreationDate {
 			approxCreatedAt, err := LoadFirstUgcContentTime(problem.Question.Data.Question.TitleSlug)
 			if err != nil {
 				log.Err(err).Msgf("failed to determine approximate creation date for %s", problem.Question.Data.Question.TitleSlug)
@@ -266,6 +297,13 @@ func downloadQuestions(slugs []QuestionSlug) int {
 				log.Err(err).Msgf("failed to read existing problem from %s", dstFile)
 				return
 			}
+			snapshot := existingProblem.appendSnapshot(problem.Question, problem.DownloadedAt)
+			if snapshot.ContentChanged || len(snapshot.ChangedSnippets) > 0 {
+				log.Info().Msgf("content of %s has changed (content: %v, snippets: %v)", dstFile, snapshot.ContentChanged, snapshot.ChangedSnippets)
+			}
+			if changedCnt := existingProblem.markChangedSolutions(snapshot); changedCnt > 0 {
+				log.Warn().Msgf("%d solution(s) of %s were prompted with outdated content", changedCnt, dstFile)
+			}
 			existingProblem.Question = problem.Question
 			// metadata. We can't overwrite the whole problem struct because we want to keep existing submissions and solutions
 			if !problem.DownloadedAt.IsZero() {
@@ -274,6 +312,12 @@ func downloadQuestions(slugs []QuestionSlug) int {
 			if !problem.CreatedAtApprox.IsZero() {
 				existingProblem.CreatedAtApprox = problem.CreatedAtApprox
 			}
+			for contentLang, translated := range problem.Translations {
+				if existingProblem.Translations == nil {
+					existingProblem.Translations = map[string]string{}
+				}
+				existingProblem.Translations[contentLang] = translated
+			}
 
 			err = existingProblem.SaveProblemInto(dstFile)
 			if err != nil {
@@ -281,6 +325,7 @@ func downloadQuestions(slugs []QuestionSlug) int {
 				return
 			}
 		} else {
+			problem.History = []QuestionSnapshot{newQuestionSnapshot(problem.Question, problem.DownloadedAt, nil)}
 			err = problem.SaveProblemInto(dstFile)
 			if err != nil {
 				log.Err(err).Msg("failed to save downloaded question")
@@ -291,11 +336,13 @@ func downloadQuestions(slugs []QuestionSlug) int {
 
 		log.Info().Msgf("Problem %s downloaded successfully", dstFile)
 		downloadedCnt += 1
+		outcome = OUTCOME_DOWNLOADED
 	})
 	c.OnError(func(r *colly.Response, e error) {
 		log.Error().Err(e).Msgf("failed to fetch question %s", r.Request.Ctx.Get("dstFile"))
 		consecutiveErrorsCnt += 1
 		errorsCnt += 1
+		tracker.add("", OUTCOME_ERROR)
 		if consecutiveErrorsCnt >= MAX_CONSECUTIVE_ERRORS {
 			log.Error().Msgf("too many errors (%d), aborting...", consecutiveErrorsCnt)
 			os.Exit(1)
@@ -305,9 +352,10 @@ func downloadQuestions(slugs []QuestionSlug) int {
 	for _, qs := range slugs {
 		if options.SkipPaid && qs.PaidOnly {
 			skippedCnt += 1
+			tracker.add("", OUTCOME_SKIPPED)
 			continue
 		}
-		dstFile := path.Join(options.Dir, qs.Stat.TitleSlug+".json")
+		dstFile := path.Join(options.Dir, leetcodeSite.ProblemFilename(qs.Stat.TitleSlug))
 		fileAlreadyExists, _ := fileExists(dstFile)
 		if fileAlreadyExists {
 			alreadyDownloadedCnt += 1
@@ -315,6 +363,7 @@ func downloadQuestions(slugs []QuestionSlug) int {
 				log.Debug().Msgf("file %s already exists, downloading for update", dstFile)
 			} else {
 				log.Debug().Msgf("file %s already exists, skipping", dstFile)
+				tracker.add("", OUTCOME_SKIPPED)
 				continue
 			}
 		}
@@ -335,6 +384,7 @@ func downloadQuestions(slugs []QuestionSlug) int {
 		if err != nil {
 			log.Err(err).Msgf("failed to create question request for %s", qs.Stat.TitleSlug)
 			errorsCnt += 1
+			tracker.add("", OUTCOME_ERROR)
 			continue
 		}
 		queuedCnt += 1
@@ -349,5 +399,6 @@ func downloadQuestions(slugs []QuestionSlug) int {
 		log.Info().Msgf("skipped: %d", skippedCnt)
 		log.Info().Msgf("errors: %d", errorsCnt)
 	}
+	tracker.finish()
 	return downloadedCnt
 }
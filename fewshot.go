@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fewShot is a solved problem shown to the model as a demonstration before the actual question
+type fewShot struct {
+	Question Question
+	// accepted code in the prompt language
+	Code string
+}
+
+// ChatShot is a demonstration turn: the prompt for a solved problem and the answer to it
+type ChatShot struct {
+	Slug      string
+	User      string
+	Assistant string
+}
+
+// loadFewShots reads few-shot problems and picks an accepted solution in the language for each of them
+func loadFewShots(files []string, lang string) ([]fewShot, error) {
+	shots := []fewShot{}
+	for _, file := range files {
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read few-shot problem %s: %w", file, err)
+		}
+		code, ok := acceptedCode(problem, lang)
+		if !ok {
+			return nil, fmt.Errorf("few-shot problem %s has no accepted %s solution", file, lang)
+		}
+		log.Debug().Msgf("Using %s as a few-shot example", problem.Question.Data.Question.TitleSlug)
+		shots = append(shots, fewShot{Question: problem.Question, Code: code})
+	}
+	return shots, nil
+}
+
+// acceptedCode finds an accepted solution of the problem in the language. Models are tried in
+// alphabetical order, so the same solution is picked on every run
+func acceptedCode(problem Problem, lang string) (string, bool) {
+	keys := []string{}
+	for key := range problem.SubmissionsV2 {
+		keys = append(keys, key)
+	}
+	for key := range problem.Submissions {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range slices.Compact(keys) {
+		submission, ok := problem.GetSubmission(key, lang)
+		if !ok || submission.CheckResponse.StatusMsg != "Accepted" {
+			continue
+		}
+		// submitted code may have a metadata comment, so the solution is used
+		if solution, ok := problem.GetSolution(key, lang); ok && solution.TypedCode != "" {
+			return solution.TypedCode, true
+		}
+	}
+	return "", false
+}
+
+// fewShotSlugs returns slugs of few-shot problems, in the order they are given
+func fewShotSlugs(files []string) []string {
+	slugs := []string{}
+	for _, file := range files {
+		slugs = append(slugs, leetcodeSite.SlugFromFilename(file))
+	}
+	return slugs
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// QuestionSnapshot records question stats and content hashes at a download,
+// along with the changes since the previous snapshot
+type QuestionSnapshot struct {
+	TakenAt          time.Time
+	Likes            int
+	Dislikes         int
+	TotalSubmissions int
+	TotalAccepted    int
+	AcRate           string
+
+	LikesDelta            int
+	DislikesDelta         int
+	TotalSubmissionsDelta int
+	TotalAcceptedDelta    int
+
+	ContentHash     string
+	SnippetHashes   map[string]string
+	ContentChanged  bool     `json:"ContentChanged,omitempty"`
+	ChangedSnippets []string `json:"ChangedSnippets,omitempty"`
+}
+
+func (q Question) ContentHash() string {
+	return hashString(q.Data.Question.Content)
+}
+
+func (q Question) SnippetHash(lang string) string {
+	return hashString(q.FindSnippet(lang))
+}
+
+// newQuestionSnapshot makes a snapshot of the question. prev is the previous snapshot, if any
+func newQuestionSnapshot(q Question, takenAt time.Time, prev *QuestionSnapshot) QuestionSnapshot {
+	// stats are parsed on read only, but the question may be just downloaded
+	err := scanAcRate(q.Data.Question.Stats, &q)
+	if err != nil {
+		log.Err(err).Msgf("failed to scan stats of %s", q.Data.Question.TitleSlug)
+	}
+
+	s := QuestionSnapshot{
+		TakenAt:          takenAt,
+		Likes:            q.Data.Question.Likes,
+		Dislikes:         q.Data.Question.Dislikes,
+		TotalSubmissions: q.TotalSubmissions,
+		TotalAccepted:    q.TotalAccepted,
+		AcRate:           q.AcRate,
+		ContentHash:      q.ContentHash(),
+		SnippetHashes:    map[string]string{},
+	}
+	for _, snippet := range q.Data.Question.CodeSnippets {
+		s.SnippetHashes[snippet.LangSlug] = hashString(snippet.Code)
+	}
+	if prev == nil {
+		return s
+	}
+
+	s.LikesDelta = s.Likes - prev.Likes
+	s.DislikesDelta = s.Dislikes - prev.Dislikes
+	s.TotalSubmissionsDelta = s.TotalSubmissions - prev.TotalSubmissions
+	s.TotalAcceptedDelta = s.TotalAccepted - prev.TotalAccepted
+	s.ContentChanged = s.ContentHash != prev.ContentHash
+	for lang, hash := range s.SnippetHashes {
+		if prevHash, ok := prev.SnippetHashes[lang]; ok && prevHash != hash {
+			s.ChangedSnippets = append(s.ChangedSnippets, lang)
+		}
+	}
+	slices.Sort(s.ChangedSnippets)
+
+	return s
+}
+
+// appendSnapshot appends a snapshot of the question downloaded at downloadedAt to the problem history.
+// Problems downloaded before the history was introduced get a snapshot of the existing question first.
+func (p *Problem) appendSnapshot(q Question, downloadedAt time.Time) QuestionSnapshot {
+	var prev *QuestionSnapshot
+	if len(p.History) > 0 {
+		prev = &p.History[len(p.History)-1]
+	} else if p.Question.Data.Question.TitleSlug != "" {
+		initial := newQuestionSnapshot(p.Question, p.DownloadedAt, nil)
+		p.History = append(p.History, initial)
+		prev = &initial
+	}
+
+	s := newQuestionSnapshot(q, downloadedAt, prev)
+	p.History = append(p.History, s)
+	return s
+}
+
+// markChangedSolutions flags solutions whose prompt was built from content which has changed since.
+// Solutions without hashes (prompted before hashes were recorded) are flagged if the latest snapshot has changes.
+func (p *Problem) markChangedSolutions(s QuestionSnapshot) int {
+	isChanged := func(sol Solution) bool {
+		if sol.ContentHash != "" {
+			return sol.ContentHash != s.ContentHash || sol.SnippetHash != s.SnippetHashes[sol.Lang]
+		}
+		return s.ContentChanged || slices.Contains(s.ChangedSnippets, sol.Lang)
+	}
+
+	changedCnt := 0
+	for modelName, modelSolutions := range p.SolutionsV2 {
+		for lang, sol := range modelSolutions {
+			if !sol.ContentChanged && isChanged(sol) {
+				sol.ContentChanged = true
+				p.SolutionsV2[modelName][lang] = sol
+				changedCnt += 1
+			}
+		}
+	}
+	for modelName, sol := range p.Solutions {
+		if !sol.ContentChanged && isChanged(sol) {
+			sol.ContentChanged = true
+			p.Solutions[modelName] = sol
+			changedCnt += 1
+		}
+	}
+
+	return changedCnt
+}
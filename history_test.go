@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestMarkChangedSolutions(t *testing.T) {
+	snapshot := QuestionSnapshot{
+		ContentHash:     "new",
+		SnippetHashes:   map[string]string{"python3": "py", "cpp": "cpp"},
+		ChangedSnippets: []string{"cpp"},
+	}
+	p := Problem{
+		Solutions: map[string]Solution{
+			// legacy solutions of python3
+			"gpt-4":    {Lang: "python3", ContentHash: "old", SnippetHash: "py"},
+			"claude-2": {Lang: "python3"},
+		},
+		SolutionsV2: map[string]map[string]Solution{
+			"gpt-4o": {
+				"python3": {Lang: "python3", ContentHash: "new", SnippetHash: "py"},
+				"cpp":     {Lang: "cpp"},
+			},
+			"o3": {
+				"python3": {Lang: "python3", ContentHash: "new", SnippetHash: "old"},
+				"cpp":     {Lang: "cpp", ContentHash: "new", SnippetHash: "cpp", ContentChanged: true},
+			},
+		},
+	}
+
+	if changedCnt := p.markChangedSolutions(snapshot); changedCnt != 3 {
+		t.Errorf("expected 3 changed solutions, got: %d", changedCnt)
+	}
+	tests := []struct {
+		name     string
+		solution Solution
+		expected bool
+	}{
+		{name: "legacy with changed content hash", solution: p.Solutions["gpt-4"], expected: true},
+		{name: "legacy without hashes", solution: p.Solutions["claude-2"], expected: false},
+		{name: "same hashes", solution: p.SolutionsV2["gpt-4o"]["python3"], expected: false},
+		{name: "changed snippet without hashes", solution: p.SolutionsV2["gpt-4o"]["cpp"], expected: true},
+		{name: "changed snippet hash", solution: p.SolutionsV2["o3"]["python3"], expected: true},
+		{name: "already flagged", solution: p.SolutionsV2["o3"]["cpp"], expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.solution.ContentChanged != test.expected {
+				t.Errorf("expected content changed: %v, got: %v", test.expected, test.solution.ContentChanged)
+			}
+		})
+	}
+}
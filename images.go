@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const IMAGES_DIR = "images"
+
+// image formats accepted by all vision models we use
+var promptImageMimeTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+var imgTagRe = regexp.MustCompile(`(?i)<img\s[^>]*?src\s*=\s*["']([^"']+)["'][^>]*>`)
+
+type QuestionImage struct {
+	Url string
+	// relative to the problems directory
+	Path     string
+	MimeType string
+}
+
+// PromptImage is an image attached to a prompt
+type PromptImage struct {
+	Url      string
+	MimeType string
+	Data     []byte
+}
+
+// downloadQuestionImages downloads images referenced in the question content into the problems directory.
+// Already downloaded images are not downloaded again.
+func downloadQuestionImages(q Question) ([]QuestionImage, error) {
+	matches := imgTagRe.FindAllStringSubmatch(q.Data.Question.Content, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	slug := q.Data.Question.TitleSlug
+	imagesDir := path.Join(IMAGES_DIR, slug)
+	err := os.MkdirAll(path.Join(options.Dir, imagesDir), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create images directory: %w", err)
+	}
+
+	images := []QuestionImage{}
+	for i, m := range matches {
+		imageUrl, err := leetcodeUrl.Parse(m[1])
+		if err != nil {
+			log.Err(err).Msgf("invalid image url %s in %s", m[1], slug)
+			continue
+		}
+		image := QuestionImage{
+			Url:      imageUrl.String(),
+			Path:     path.Join(imagesDir, fmt.Sprintf("%d-%s", i+1, imageFilename(imageUrl))),
+			MimeType: mime.TypeByExtension(path.Ext(imageUrl.Path)),
+		}
+		dstFile := path.Join(options.Dir, image.Path)
+		if ok, _ := fileExists(dstFile); ok {
+			log.Debug().Msgf("image %s already downloaded", dstFile)
+			images = append(images, image)
+			continue
+		}
+
+		mimeType, err := downloadImage(image.Url, dstFile)
+		if err != nil {
+			log.Err(err).Msgf("failed to download image %s", image.Url)
+			continue
+		}
+		if mimeType != "" {
+			image.MimeType = mimeType
+		}
+		log.Debug().Msgf("downloaded image %s into %s", image.Url, dstFile)
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+func imageFilename(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "image"
+	}
+	// keep filenames safe for any filesystem
+	return regexp.MustCompile(`[^\w.-]+`).ReplaceAllString(name, "_")
+}
+
+func downloadImage(imageUrl, dstFile string) (string, error) {
+	resp, err := client().Get(imageUrl)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("non-ok http response code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	err = os.WriteFile(dstFile, data, 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	return mimeType, nil
+}
+
+// loadPromptImages reads downloaded question images. Images in formats not supported by models are skipped
+func loadPromptImages(q Question) ([]PromptImage, error) {
+	images := []PromptImage{}
+	for _, image := range q.Images {
+		if !slices.Contains(promptImageMimeTypes, image.MimeType) {
+			log.Warn().Msgf("skipping image %s: unsupported type %s", image.Path, image.MimeType)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(options.Dir, image.Path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		images = append(images, PromptImage{Url: image.Url, MimeType: image.MimeType, Data: data})
+	}
+
+	return images, nil
+}
+
+// replaceImageTags replaces <img> tags with text markers, so the prompt can refer to attached images
+func replaceImageTags(content string, images []PromptImage) string {
+	return imgTagRe.ReplaceAllStringFunc(content, func(tag string) string {
+		m := imgTagRe.FindStringSubmatch(tag)
+		imageUrl, err := leetcodeUrl.Parse(m[1])
+		if err == nil {
+			idx := slices.IndexFunc(images, func(image PromptImage) bool { return image.Url == imageUrl.String() })
+			if idx != -1 {
+				return fmt.Sprintf("[image %d]", idx+1)
+			}
+		}
+		return "[image is not available]"
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// journal events
+const (
+	JOURNAL_START  = "start"
+	JOURNAL_PROMPT = "prompt"
+	JOURNAL_SUBMIT = "submit"
+	JOURNAL_END    = "end"
+)
+
+// classes of errors in journal events
+const (
+	ERROR_CLASS_FATAL         = "fatal"
+	ERROR_CLASS_NON_RETRIABLE = "non_retriable"
+	ERROR_CLASS_REJECTED      = "rejected"
+	ERROR_CLASS_INVALID_CODE  = "invalid_code"
+	ERROR_CLASS_TIMEOUT       = "timeout"
+	ERROR_CLASS_CANCELED      = "canceled"
+	ERROR_CLASS_OTHER         = "other"
+)
+
+// JournalEvent is a line of the run journal. A journal without the end event belongs to an interrupted run
+type JournalEvent struct {
+	Time    time.Time
+	Run     string
+	Command string
+	Event   string
+	Args    []string `json:",omitempty"`
+	Problem string   `json:",omitempty"`
+	Model   string   `json:",omitempty"`
+	Lang    string   `json:",omitempty"`
+	// outcome as counted in the summary
+	Status       string  `json:",omitempty"`
+	StatusMsg    string  `json:",omitempty"`
+	Latency      float64 `json:",omitempty"`
+	PromptTokens int     `json:",omitempty"`
+	OutputTokens int     `json:",omitempty"`
+	FromCache    bool    `json:",omitempty"`
+	// leetcode failure class of not accepted submissions, or the class of the error
+	ErrorClass string         `json:",omitempty"`
+	Error      string         `json:",omitempty"`
+	Counts     map[string]int `json:",omitempty"`
+}
+
+// journal appends events of a run to a jsonl file in journal_dir. Every event is written at once,
+// so the journal of an interrupted run is complete up to the interruption
+type journal struct {
+	run     string
+	command string
+	mu      sync.Mutex
+	file    *os.File
+}
+
+// openJournal starts the journal of the command with its command line, nil if journal_dir is not set.
+// A nil journal records nothing
+func openJournal(command string) *journal {
+	if options.JournalDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(options.JournalDir, 0o755)
+	if err != nil {
+		log.Err(err).Msg("Failed to create the journal dir, the run is not journaled")
+		return nil
+	}
+	now := time.Now()
+	run := fmt.Sprintf("%s-%s-%d", command, now.Format("20060102-150405"), os.Getpid())
+	path := filepath.Join(options.JournalDir, run+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Err(err).Msg("Failed to open the journal, the run is not journaled")
+		return nil
+	}
+	log.Debug().Msgf("Journaling the run into %s", path)
+	j := &journal{run: run, command: command, file: file}
+	j.record(JournalEvent{Event: JOURNAL_START, Args: os.Args[1:]})
+	return j
+}
+
+func (j *journal) record(e JournalEvent) {
+	if j == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Run = j.run
+	e.Command = j.command
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Err(err).Msg("Failed to marshal the journal event")
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.file.Write(append(data, '\n'))
+	if err != nil {
+		log.Err(err).Msg("Failed to write the journal event")
+	}
+}
+
+// close records the end of the run with the counts of outcomes
+func (j *journal) close(summary RunSummary) {
+	if j == nil {
+		return
+	}
+	j.record(JournalEvent{Event: JOURNAL_END, Counts: summary.Counts})
+	err := j.file.Close()
+	if err != nil {
+		log.Err(err).Msg("Failed to close the journal")
+	}
+}
+
+// solutionEvent fills the event with metrics of the solution
+func solutionEvent(e JournalEvent, s *Solution) JournalEvent {
+	if s == nil {
+		return e
+	}
+	e.Latency = s.Latency.Seconds()
+	e.PromptTokens = s.PromptTokens
+	e.OutputTokens = s.OutputTokens
+	e.FromCache = s.FromCache
+	return e
+}
+
+// errorEvent fills the event with the error and its class
+func errorEvent(e JournalEvent, err error) JournalEvent {
+	if err == nil {
+		return e
+	}
+	e.Error = err.Error()
+	e.ErrorClass = classifyError(err)
+	return e
+}
+
+func classifyError(err error) string {
+	var rejErr RejectedError
+	var codeErr InvalidCodeError
+	switch {
+	case errors.Is(err, ErrFatal):
+		return ERROR_CLASS_FATAL
+	case errors.Is(err, ErrNonRetriable):
+		return ERROR_CLASS_NON_RETRIABLE
+	case errors.As(err, &rejErr):
+		return ERROR_CLASS_REJECTED
+	case errors.As(err, &codeErr):
+		return ERROR_CLASS_INVALID_CODE
+	case errors.Is(err, context.DeadlineExceeded):
+		return ERROR_CLASS_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		return ERROR_CLASS_CANCELED
+	}
+	return ERROR_CLASS_OTHER
+}
@@ -12,12 +12,10 @@ import (
 	"net/url"
 	"path"
 	"slices"
+	"strconv"
 	"time"
 
 	cloudflarebp "github.com/DaRealFreak/cloudflare-bp-go"
-	"github.com/browserutils/kooky"
-	_ "github.com/browserutils/kooky/browser/chrome"
-	_ "github.com/browserutils/kooky/browser/firefox"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -61,22 +59,11 @@ type QuestionDiscussCommentsResp struct {
 }
 
 var cookieJarCache http.CookieJar
+
+// set by initSite
 var leetcodeUrl *url.URL
 var leetcodeGraphqlUrl *url.URL
 
-func init() {
-	u, err := url.Parse("https://leetcode.com/")
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse leetcode url: %v. This is a bug", err))
-	}
-	leetcodeUrl = u
-	leetcodeGraphqlUrl = &url.URL{
-		Scheme: u.Scheme,
-		Host:   u.Host,
-		Path:   "/graphql",
-	}
-}
-
 func makeNiceReferer(urlStr string) (string, error) {
 	url, err := url.Parse(urlStr)
 	if err != nil {
@@ -138,30 +125,24 @@ func cookieJar() http.CookieJar {
 	return cookieJarCache
 }
 
-// loadCookieJar tries to find a suitable cookie jar from the firefox browser's default profile
-// modern chrome cookies are encrypted and not supported yet
+// loadCookieJar loads leetcode cookies from the active session source
 func loadCookieJar() (http.CookieJar, error) {
-	ctx := context.TODO()
-	for _, cookieStore := range kooky.FindAllCookieStores(ctx) {
-		if ok, _ := fileExists(cookieStore.FilePath()); !ok {
-			// skip non-existing cookie stores
-			continue
-		}
-		log.Trace().Msgf("Found cookie store for %s: %s (default: %v)", cookieStore.Browser(), cookieStore.FilePath(), cookieStore.IsDefaultProfile())
-		if cookieStore.Browser() == "firefox" && cookieStore.IsDefaultProfile() {
-			subJar, err := cookieStore.SubJar(ctx, kooky.Valid, kooky.DomainHasSuffix(leetcodeUrl.Host))
-			defer cookieStore.Close()
-
-			if err != nil {
-				log.Err(err).Msgf("failed to get cookie sub jar from %s", cookieStore.FilePath())
-				continue
-			}
-			log.Debug().Msgf("using cookie jar from %s", cookieStore.FilePath())
-			return subJar, nil
-		}
+	source := activeSessionSource()
+	cookies, err := source.Cookies(context.TODO(), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cookies from %s: %w", source.Name(), err)
+	}
+	if !slices.ContainsFunc(cookies, func(c *http.Cookie) bool { return c.Name == SESSION_COOKIE }) {
+		log.Warn().Msgf("no %s cookie found in %s", SESSION_COOKIE, source.Name())
 	}
 
-	return nil, fmt.Errorf("no cookie stores found")
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	jar.SetCookies(leetcodeUrl, cookies)
+	log.Debug().Msgf("using %d cookie(s) from %s", len(cookies), source.Name())
+	return jar, nil
 }
 
 func cookie(name string) (*http.Cookie, error) {
@@ -219,16 +200,12 @@ func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 }
 
 // &http.Transport{} bypasses cloudflare generally better than DefaultTransport
+// all transports share the same leetcode rate limiter
 func newTransport() http.RoundTripper {
-    return &debugTransport{base: cloudflarebp.AddCloudFlareByPass(&http.Transport{})}
-}
-
-func SubmitUrl(q Question) string {
-	return leetcodeUrl.Scheme + "://" + leetcodeUrl.Host + "/problems/" + q.Data.Question.TitleSlug + "/submit/"
-}
-
-func SubmissionCheckUrl(submissionId uint64) string {
-	return leetcodeUrl.Scheme + "://" + leetcodeUrl.Host + "/submissions/detail/" + fmt.Sprint(submissionId) + "/check/"
+	return &rateLimitedTransport{
+		base:    &debugTransport{base: cloudflarebp.AddCloudFlareByPass(&http.Transport{})},
+		limiter: leetcodeRateLimiter(),
+	}
 }
 
 // not used, but may be useful in the future
@@ -505,6 +482,110 @@ func LoadFirstUgcContentTime(slug string) (time.Time, error) {
 	return firstSolutionTime, nil
 }
 
+type ProblemsetQuestionList struct {
+	Data struct {
+		ProblemsetQuestionList struct {
+			Total     int
+			Questions []struct {
+				FrontendQuestionId string
+				PaidOnly           bool
+				TitleSlug          string
+			}
+		}
+	}
+}
+
+func ProblemsetQuestionListQuery(category string, limit, skip int) ([]byte, error) {
+	graphql := `query problemsetQuestionList($categorySlug: String, $limit: Int, $skip: Int, $filters: QuestionListFilterInput)
+		{
+			problemsetQuestionList: questionList(
+				categorySlug: $categorySlug
+				limit: $limit
+				skip: $skip
+				filters: $filters
+			) {
+				total: totalNum
+				questions: data {
+					frontendQuestionId: questionFrontendId
+					paidOnly: isPaidOnly
+					titleSlug
+				}
+			}
+		}`
+	if leetcodeSite.HasLegacyQuestionList {
+		graphql = `query problemsetQuestionList($categorySlug: String, $limit: Int, $skip: Int, $filters: QuestionListFilterInput)
+		{
+			problemsetQuestionList(
+				categorySlug: $categorySlug
+				limit: $limit
+				skip: $skip
+				filters: $filters
+			) {
+				total
+				questions {
+					frontendQuestionId
+					paidOnly
+					titleSlug
+				}
+			}
+		}`
+	}
+	query := map[string]interface{}{
+		"query": graphql,
+		"variables": map[string]interface{}{
+			"categorySlug": category,
+			"limit":        limit,
+			"skip":         skip,
+			"filters":      map[string]interface{}{},
+		},
+		"operationName": "problemsetQuestionList",
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling GraphQL: %w", err)
+	}
+	return queryBytes, nil
+}
+
+// LoadProblemsetQuestionSlugs lists question slugs of a category page by page.
+// Used for categories which are not available via the problems api, like pandas or javascript.
+func LoadProblemsetQuestionSlugs(category string) ([]QuestionSlug, error) {
+	perPage := 100
+	slugs := []QuestionSlug{}
+	for {
+		queryBytes, err := ProblemsetQuestionListQuery(category, perPage, len(slugs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create query to get question list: %w", err)
+		}
+		respBody, _, err := makeAuthorizedHttpRequest("POST", leetcodeGraphqlUrl.String(), bytes.NewReader(queryBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get question list: %w", err)
+		}
+		log.Trace().Msgf("got response body: %s", respBody)
+
+		var resp ProblemsetQuestionList
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		questions := resp.Data.ProblemsetQuestionList.Questions
+		for _, q := range questions {
+			var qs QuestionSlug
+			qs.Stat.FrontendId, err = strconv.Atoi(q.FrontendQuestionId)
+			if err != nil {
+				log.Err(err).Msgf("invalid frontend id for %s", q.TitleSlug)
+			}
+			qs.Stat.TitleSlug = q.TitleSlug
+			qs.PaidOnly = q.PaidOnly
+			slugs = append(slugs, qs)
+		}
+		if len(questions) == 0 || len(slugs) >= resp.Data.ProblemsetQuestionList.Total {
+			break
+		}
+	}
+
+	return slugs, nil
+}
+
 // UserStatus contains only the username from Leetcode globalData API
 type UserStatus struct {
 	Username string
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// the limiter never slows down below this rate, no matter how many 429s we get
+	LIMITER_MIN_RATE = 1.0 / 300.0
+	// backoff used when leetcode does not tell us how long to wait
+	LIMITER_BASE_BACKOFF = 30 * time.Second
+	LIMITER_MAX_BACKOFF  = 15 * time.Minute
+)
+
+// leetcodeLimiter is a token bucket shared by all requests to leetcode.
+// It slows down on 429 responses and cloudflare challenges and speeds up again on successful responses.
+// The state is kept in a file guarded by a lock file, so concurrent processes share the same budget.
+type leetcodeLimiter struct {
+	mu        sync.Mutex
+	statePath string
+	maxRate   float64
+	burst     float64
+	// unlimited limiter only honors backoffs
+	unlimited bool
+	state     leetcodeLimiterState
+}
+
+type leetcodeLimiterState struct {
+	Tokens       float64
+	Rate         float64
+	UpdatedAt    time.Time
+	BlockedUntil time.Time
+	// number of consecutive throttled responses, used for exponential backoff
+	Penalties int
+}
+
+var sharedLimiter *leetcodeLimiter
+var sharedLimiterOnce sync.Once
+
+// leetcodeRateLimiter returns the limiter configured from options, shared by all leetcode clients in the process
+func leetcodeRateLimiter() *leetcodeLimiter {
+	sharedLimiterOnce.Do(func() {
+		statePath := options.LeetcodeLimiterState
+		if statePath == "" {
+			statePath = filepath.Join(os.TempDir(), "leetgptsolver-limiter-"+leetcodeUrl.Host+".json")
+		}
+		sharedLimiter = newLeetcodeLimiter(statePath, options.LeetcodeRateLimit, options.LeetcodeRateBurst)
+		log.Debug().Msgf("Leetcode limiter configured: rate=%0.6f req/s burst=%d state=%s", options.LeetcodeRateLimit, options.LeetcodeRateBurst, statePath)
+	})
+	return sharedLimiter
+}
+
+func newLeetcodeLimiter(statePath string, maxRate float64, burst int) *leetcodeLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &leetcodeLimiter{
+		statePath: statePath,
+		maxRate:   maxRate,
+		burst:     float64(burst),
+		unlimited: maxRate <= 0,
+		state: leetcodeLimiterState{
+			Tokens: float64(burst),
+			Rate:   maxRate,
+		},
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done
+func (l *leetcodeLimiter) Wait(ctx context.Context) error {
+	for {
+		var wait time.Duration
+		l.update(ctx, func(s *leetcodeLimiterState, now time.Time) {
+			if now.Before(s.BlockedUntil) {
+				wait = s.BlockedUntil.Sub(now)
+				return
+			}
+			if s.Tokens >= 1 {
+				s.Tokens -= 1
+				wait = 0
+				return
+			}
+			wait = time.Duration((1 - s.Tokens) / s.Rate * float64(time.Second))
+		})
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if wait <= 0 {
+			return nil
+		}
+
+		log.Trace().Msgf("leetcode limiter: waiting %s", wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Throttled slows the limiter down after leetcode asked us to. retryAfter is honored if positive
+func (l *leetcodeLimiter) Throttled(retryAfter time.Duration) {
+	l.update(context.Background(), func(s *leetcodeLimiterState, now time.Time) {
+		s.Penalties += 1
+		if !l.unlimited {
+			s.Rate = max(s.Rate/2, LIMITER_MIN_RATE)
+			s.Tokens = 0
+		}
+
+		backoff := retryAfter
+		if backoff <= 0 {
+			backoff = min(LIMITER_BASE_BACKOFF<<min(s.Penalties-1, 10), LIMITER_MAX_BACKOFF)
+		}
+		if blockedUntil := now.Add(backoff); blockedUntil.After(s.BlockedUntil) {
+			s.BlockedUntil = blockedUntil
+		}
+		log.Warn().Msgf("leetcode is throttling requests, backing off for %s (rate is %0.4f req/s now)", backoff.Round(time.Second), s.Rate)
+	})
+}
+
+// Succeeded gradually restores the rate after successful responses
+func (l *leetcodeLimiter) Succeeded() {
+	l.update(context.Background(), func(s *leetcodeLimiterState, now time.Time) {
+		s.Penalties = 0
+		if l.unlimited {
+			return
+		}
+		s.Rate = min(s.Rate+l.maxRate/10, l.maxRate)
+	})
+}
+
+// update loads the shared state, refills the bucket, applies fn and stores the state back.
+// Problems with the state or lock files are logged, and the limiter falls back to the in-process state.
+func (l *leetcodeLimiter) update(ctx context.Context, fn func(s *leetcodeLimiterState, now time.Time)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := acquireLockFile(ctx, l.statePath+".lock")
+	if err != nil {
+		log.Err(err).Msg("failed to lock leetcode limiter state, using in-process state")
+	} else {
+		defer unlock()
+		if err := l.load(); err != nil {
+			log.Err(err).Msgf("failed to load leetcode limiter state from %s", l.statePath)
+		}
+	}
+
+	now := time.Now()
+	s := &l.state
+	if s.Rate <= 0 || s.Rate > l.maxRate {
+		s.Rate = l.maxRate
+	}
+	if l.unlimited {
+		s.Tokens = l.burst
+	} else if !s.UpdatedAt.IsZero() && now.After(s.UpdatedAt) {
+		s.Tokens = min(s.Tokens+now.Sub(s.UpdatedAt).Seconds()*s.Rate, l.burst)
+	}
+	s.UpdatedAt = now
+	fn(s, now)
+
+	if unlock != nil {
+		if err := l.save(); err != nil {
+			log.Err(err).Msgf("failed to save leetcode limiter state into %s", l.statePath)
+		}
+	}
+}
+
+func (l *leetcodeLimiter) load() error {
+	contents, err := os.ReadFile(l.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s leetcodeLimiterState
+	if err := json.Unmarshal(contents, &s); err != nil {
+		return fmt.Errorf("failed to unmarshal limiter state: %w", err)
+	}
+	l.state = s
+	return nil
+}
+
+func (l *leetcodeLimiter) save() error {
+	contents, err := json.Marshal(l.state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.statePath, contents, 0o644)
+}
+
+// parseRetryAfter supports both forms of the Retry-After header: delay in seconds and http date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// isChallenge detects cloudflare challenges: they come as 403 with html instead of the expected json
+func isChallenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return resp.Header.Get("Cf-Mitigated") == "challenge" || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
+
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *leetcodeLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// other hosts (e.g. static assets) are not limited
+	if req.URL.Host != leetcodeUrl.Host {
+		return t.base.RoundTrip(req)
+	}
+
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || isChallenge(resp) {
+		t.limiter.Throttled(parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	} else if resp.StatusCode < http.StatusBadRequest {
+		t.limiter.Succeeded()
+	}
+
+	return resp, nil
+}
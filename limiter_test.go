@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "empty", value: "", expected: 0},
+		{name: "seconds", value: "120", expected: 2 * time.Minute},
+		{name: "seconds with spaces", value: " 5 ", expected: 5 * time.Second},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), expected: 90 * time.Second},
+		{name: "http date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), expected: -time.Minute},
+		{name: "invalid", value: "soon", expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseRetryAfter(test.value, now); got != test.expected {
+				t.Errorf("expected: %s, got: %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestLeetcodeLimiterRefillAndBurst(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "limiter.json")
+	l := newLeetcodeLimiter(statePath, 10, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 2 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected the burst without waiting, took %s", elapsed)
+	}
+
+	// the bucket is empty, the next token comes in 1/10 of a second
+	start = time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected to wait for the refill, took %s", elapsed)
+	}
+
+	// another process shares the budget through the state file
+	other := newLeetcodeLimiter(statePath, 10, 2)
+	start = time.Now()
+	if err := other.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected the shared bucket to be empty, took %s", elapsed)
+	}
+}
+
+func TestLeetcodeLimiterBackoff(t *testing.T) {
+	l := newLeetcodeLimiter(filepath.Join(t.TempDir(), "limiter.json"), 1, 1)
+
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		penalties  int
+		// minimal time the limiter is blocked for after the response
+		expectedBackoff time.Duration
+		expectedRate    float64
+	}{
+		{name: "first throttling", penalties: 1, expectedBackoff: LIMITER_BASE_BACKOFF, expectedRate: 0.5},
+		{name: "backoff doubles", penalties: 2, expectedBackoff: 2 * LIMITER_BASE_BACKOFF, expectedRate: 0.25},
+		{name: "shorter retry-after does not shorten the backoff", retryAfter: time.Second, penalties: 3, expectedBackoff: 2*LIMITER_BASE_BACKOFF - time.Second, expectedRate: 0.125},
+		{name: "longer retry-after", retryAfter: 10 * time.Minute, penalties: 4, expectedBackoff: 10 * time.Minute, expectedRate: 0.0625},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := time.Now()
+			l.Throttled(test.retryAfter)
+			if l.state.Penalties != test.penalties {
+				t.Errorf("expected penalties: %d, got: %d", test.penalties, l.state.Penalties)
+			}
+			if backoff := l.state.BlockedUntil.Sub(before); backoff < test.expectedBackoff || backoff > test.expectedBackoff+time.Second {
+				t.Errorf("expected backoff: %s, got: %s", test.expectedBackoff, backoff)
+			}
+			if l.state.Rate != test.expectedRate {
+				t.Errorf("expected rate: %v, got: %v", test.expectedRate, l.state.Rate)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("expected a blocked limiter to wait until the context is done")
+	}
+
+	l.Succeeded()
+	if l.state.Penalties != 0 {
+		t.Errorf("expected penalties to reset after a success, got: %d", l.state.Penalties)
+	}
+	if l.state.Rate != 0.0625+0.1 {
+		t.Errorf("expected the rate to grow by a tenth of the max rate, got: %v", l.state.Rate)
+	}
+}
+
+func TestLeetcodeLimiterUnlimited(t *testing.T) {
+	l := newLeetcodeLimiter(filepath.Join(t.TempDir(), "limiter.json"), 0, 1)
+	start := time.Now()
+	for range 100 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected no waiting without a limit, took %s", elapsed)
+	}
+
+	// backoffs are honored anyway
+	l.Throttled(time.Minute)
+	if l.state.Rate != 0 {
+		t.Errorf("expected the rate of an unlimited limiter to stay 0, got: %v", l.state.Rate)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("expected a throttled unlimited limiter to wait")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// rough number of characters per token, used to estimate prompt tokens before the request
+const CHARS_PER_TOKEN = 4
+
+// VendorLimit overrides prompt_parallelism, prompt_rate_limit and prompt_rate_burst for a vendor.
+// Zero fields fall back to these options
+type VendorLimit struct {
+	Parallelism     int     `mapstructure:"parallelism"`
+	RateLimit       float64 `mapstructure:"rate_limit"`
+	RateBurst       int     `mapstructure:"rate_burst"`
+	TokensPerMinute int     `mapstructure:"tokens_per_minute"`
+}
+
+// vendorLimiter limits prompts of all models of a vendor in the run
+type vendorLimiter struct {
+	name        string
+	parallelism int
+	requests    *rate.Limiter
+	// nil without tokens_per_minute
+	tokens *rate.Limiter
+}
+
+// newVendorLimiters creates independent limiters of the vendors, vendors missing in vendor_limits get
+// the prompt_* limits each
+func newVendorLimiters(vendors []int) (map[int]*vendorLimiter, error) {
+	configured := map[int]VendorLimit{}
+	for name, limit := range options.VendorLimits {
+		vendor, err := leetgptsolver.ParseModelVendor(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vendor_limits: %w", err)
+		}
+		configured[vendor] = limit
+	}
+
+	limiters := map[int]*vendorLimiter{}
+	for _, vendor := range vendors {
+		limit := configured[vendor]
+		if limit.Parallelism <= 0 {
+			limit.Parallelism = options.PromptParallelism
+		}
+		if limit.RateLimit <= 0 {
+			limit.RateLimit = options.PromptRateLimit
+		}
+		if limit.RateBurst <= 0 {
+			limit.RateBurst = options.PromptRateBurst
+		}
+		l := &vendorLimiter{
+			name:        leetgptsolver.ModelVendorName(vendor),
+			parallelism: limit.Parallelism,
+			requests:    rate.NewLimiter(rate.Limit(limit.RateLimit), limit.RateBurst),
+		}
+		if limit.TokensPerMinute > 0 {
+			l.tokens = rate.NewLimiter(rate.Limit(float64(limit.TokensPerMinute)/60), limit.TokensPerMinute)
+		}
+		log.Debug().Msgf("Prompt limiter of %s configured: parallelism=%d rate=%0.6f req/s burst=%d tpm=%d", l.name, limit.Parallelism, limit.RateLimit, limit.RateBurst, limit.TokensPerMinute)
+		limiters[vendor] = l
+	}
+	return limiters, nil
+}
+
+// wait blocks until the request with the estimated number of prompt tokens may be sent
+func (l *vendorLimiter) wait(ctx context.Context, p *ChatPrompt) error {
+	err := l.requests.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	if l.tokens == nil {
+		return nil
+	}
+	return l.tokens.WaitN(ctx, min(estimateTokens(p), l.tokens.Burst()))
+}
+
+// charge takes tokens used by the answer beyond the estimate from the budget. Following requests
+// wait for them, the current one is already sent
+func (l *vendorLimiter) charge(p *ChatPrompt, solution *Solution) {
+	if l.tokens == nil {
+		return
+	}
+	used := solution.PromptTokens + solution.OutputTokens - min(estimateTokens(p), l.tokens.Burst())
+	if used > 0 {
+		l.tokens.ReserveN(time.Now(), min(used, l.tokens.Burst()))
+	}
+}
+
+func estimateTokens(p *ChatPrompt) int {
+	chars := len(p.System) + len(p.Text)
+	for _, shot := range p.Shots {
+		chars += len(shot.User) + len(shot.Assistant)
+	}
+	return chars/CHARS_PER_TOKEN + 1
+}
@@ -17,7 +17,7 @@ type Result struct {
 	OrderBy any
 }
 
-func list(args []string, whereExpr, orderByExpr, printExpr string, printHeader bool) {
+func list(args []string, category, whereExpr, orderByExpr, printExpr string, printHeader bool) {
 	files, err := filenamesFromArgs(args)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to get files")
@@ -67,6 +67,9 @@ outerLoop:
 			log.Err(err).Msg("failed to read the problem")
 			continue
 		}
+		if category != "" && problem.Question.Category != category {
+			continue
+		}
 
 		pStruct, err := problemToMap(problem)
 		if err != nil {
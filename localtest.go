@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/rs/zerolog/log"
+)
+
+// wall clock limit for all examples of a problem, cpu and memory are limited by the driver
+const LOCALTEST_TIMEOUT = 1 * time.Minute
+
+// driver prints results on a line with this prefix, so prints of the model code do not interfere
+const LOCALTEST_RESULT_PREFIX = "__LOCALTEST_RESULT__"
+
+// isolation of the model code, which otherwise runs with access to everything the user can access
+const (
+	// read-only filesystem except the test dir and a private /tmp, no network, own pid namespace
+	LOCALTEST_ISOLATION_BWRAP = "bwrap"
+	// no network only, for systems without bubblewrap
+	LOCALTEST_ISOLATION_UNSHARE = "unshare"
+	LOCALTEST_ISOLATION_NONE    = "none"
+)
+
+// LocalTest is the result of running the solution on examples from the problem statement
+type LocalTest struct {
+	Passed   int
+	Total    int
+	Examples []ExampleResult
+	TestedAt time.Time
+}
+
+type ExampleResult struct {
+	Input    string
+	Expected string
+	Actual   string `json:"Actual,omitempty"`
+	Passed   bool
+	Error    string `json:"Error,omitempty"`
+}
+
+type localtestSpec struct {
+	Method   string     `json:"method"`
+	AnyOrder bool       `json:"any_order"`
+	Examples []testCase `json:"examples"`
+}
+
+type testCase struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+var pythonMethodRe = regexp.MustCompile(`(?m)^class Solution\b[^\n]*\n(?:[ \t]*(?:#[^\n]*)?\n)*[ \t]+def\s+(\w+)\s*\(\s*self`)
+
+func localtest(args []string, lang, modelName string) {
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+	if lang != "python3" {
+		log.Error().Msgf("Local tests are supported only for python3, not %s", lang)
+		return
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		log.Err(err).Msg("python3 is not available")
+		return
+	}
+	switch options.LocaltestIsolation {
+	case LOCALTEST_ISOLATION_BWRAP, LOCALTEST_ISOLATION_UNSHARE:
+		if _, err := exec.LookPath(options.LocaltestIsolation); err != nil {
+			log.Err(err).Msgf("%s is not available, use another --localtest_isolation", options.LocaltestIsolation)
+			return
+		}
+	case LOCALTEST_ISOLATION_NONE:
+		log.Warn().Msg("Model code runs without isolation, it can access files and network as the user")
+	default:
+		log.Error().Msgf("invalid localtest_isolation: %s", options.LocaltestIsolation)
+		return
+	}
+
+	key := resultKey(modelName)
+	log.Info().Msgf("Testing %d solutions...", len(files))
+	passedCnt := 0
+	failedCnt := 0
+	skippedCnt := 0
+	errorsCnt := 0
+	for i, file := range files {
+		log.Info().Msgf("[%d/%d] Testing problem %s ...", i+1, len(files), file)
+
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			log.Err(err).Msg("Failed to read the problem")
+			errorsCnt += 1
+			continue
+		}
+		solution, ok := problem.GetSolution(key, lang)
+		if !ok || solution.TypedCode == "" {
+			log.Warn().Msgf("Model %s has no solution in %s to test", key, lang)
+			skippedCnt += 1
+			continue
+		}
+		if solution.LocalTest != nil && !options.Force {
+			log.Info().Msgf("Already tested at %s", solution.LocalTest.TestedAt.String())
+			skippedCnt += 1
+			continue
+		}
+		spec, err := newLocaltestSpec(problem.Question, lang)
+		if err != nil {
+			log.Warn().Msgf("Skipping problem: %v", err)
+			skippedCnt += 1
+			continue
+		}
+
+		localTest, err := runLocaltest(problem.Question, solution, spec)
+		if err != nil {
+			log.Err(err).Msg("Failed to test the solution")
+			errorsCnt += 1
+			continue
+		}
+		log.Info().Msgf("Examples passed: %d/%d", localTest.Passed, localTest.Total)
+		if localTest.Passed == localTest.Total {
+			passedCnt += 1
+		} else {
+			failedCnt += 1
+		}
+
+		solution.LocalTest = localTest
+		problem.setSolution(key, lang, solution)
+		if !options.DryRun {
+			err = problem.SaveProblemInto(file)
+			if err != nil {
+				log.Err(err).Msg("Failed to save the test result")
+				errorsCnt += 1
+				continue
+			}
+		}
+	}
+	log.Info().Msgf("Files processed: %d", len(files))
+	log.Info().Msgf("Skipped problems: %d", skippedCnt)
+	log.Info().Msgf("Solutions tested: %d (passed all examples: %d, failed: %d)", passedCnt+failedCnt, passedCnt, failedCnt)
+	log.Info().Msgf("Errors: %d", errorsCnt)
+}
+
+// newLocaltestSpec makes test cases from examples in the problem statement
+func newLocaltestSpec(q Question, lang string) (localtestSpec, error) {
+	m := pythonMethodRe.FindStringSubmatch(q.FindSnippet(lang))
+	if m == nil {
+		// design problems, like "Implement Trie", are called through a sequence of operations
+		return localtestSpec{}, errors.New("no Solution method in the snippet")
+	}
+	text := htmlToPlaintext(q.Data.Question.Content)
+	spec := localtestSpec{
+		Method:   m[1],
+		AnyOrder: strings.Contains(strings.ToLower(text), "in any order"),
+	}
+	for _, e := range leetgptsolver.ParseExamples(text) {
+		if e.Input == "" || e.Output == "" {
+			continue
+		}
+		spec.Examples = append(spec.Examples, testCase{Input: e.Input, Output: e.Output})
+	}
+	if len(spec.Examples) == 0 {
+		return localtestSpec{}, errors.New("no examples with input and output found")
+	}
+	return spec, nil
+}
+
+// runLocaltest runs the code which would be submitted with the python driver in a subprocess
+func runLocaltest(q Question, s Solution, spec localtestSpec) (*LocalTest, error) {
+	code, _, err := codeToSubmit(q, s, CODE_VARIANT_PLAIN)
+	if err != nil {
+		return nil, err
+	}
+	prelude := fmt.Sprintf(localtestPythonPrelude, options.LocaltestMemoryMb, options.LocaltestTimeLimit*len(spec.Examples)+1)
+	definitions := leetgptsolver.UncommentDefinitions(q.FindSnippet(s.Lang))
+	source := prelude + "\n" + definitions + "\n" + code + "\n" + fmt.Sprintf(localtestPythonDriver, options.LocaltestTimeLimit)
+
+	dir, err := os.MkdirTemp("", "leetgptsolver-localtest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "spec.json"), specBytes, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write spec: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "solution.py"), []byte(source), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the source: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LOCALTEST_TIMEOUT)
+	defer cancel()
+	cmd, err := localtestCommand(ctx, dir, "solution.py", "spec.json")
+	if err != nil {
+		return nil, err
+	}
+	// no inherited environment: api keys have nothing to do in the model code
+	cmd.Env = []string{"PATH=/usr/bin:/bin", "HOME=" + dir, "PYTHONDONTWRITEBYTECODE=1"}
+	log.Trace().Msgf("Running local test with source:\n%s", source)
+	output, runErr := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("local test timed out: %w", ctx.Err())
+	}
+
+	var results []ExampleResult
+	for _, line := range strings.Split(string(output), "\n") {
+		if after, ok := strings.CutPrefix(line, LOCALTEST_RESULT_PREFIX); ok {
+			err = json.Unmarshal([]byte(after), &results)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse driver results: %w", err)
+			}
+		}
+	}
+	if results == nil {
+		// the code failed before the driver, e.g. a syntax error or an exceeded limit on import
+		msg := strings.TrimSpace(string(output))
+		if msg == "" && runErr != nil {
+			msg = runErr.Error()
+		}
+		results = []ExampleResult{}
+		for _, e := range spec.Examples {
+			results = append(results, ExampleResult{Input: e.Input, Expected: e.Output, Error: msg})
+		}
+	}
+
+	localTest := &LocalTest{Total: len(results), Examples: results, TestedAt: time.Now()}
+	for _, r := range results {
+		if r.Passed {
+			localTest.Passed += 1
+		}
+	}
+	return localTest, nil
+}
+
+// localtestCommand runs python3 with the args in the dir with the configured isolation
+func localtestCommand(ctx context.Context, dir string, args ...string) (*exec.Cmd, error) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		return nil, err
+	}
+	// symlinks like pyenv shims are resolved, so the interpreter is found in the isolated environment
+	python, err = filepath.EvalSymlinks(python)
+	if err != nil {
+		return nil, err
+	}
+	var cmd *exec.Cmd
+	switch options.LocaltestIsolation {
+	case LOCALTEST_ISOLATION_BWRAP:
+		bwrapArgs := []string{
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--bind", dir, dir,
+			"--chdir", dir,
+			"--unshare-all",
+			"--die-with-parent",
+			"--new-session",
+			python,
+		}
+		cmd = exec.CommandContext(ctx, "bwrap", append(bwrapArgs, args...)...)
+	case LOCALTEST_ISOLATION_UNSHARE:
+		cmd = exec.CommandContext(ctx, "unshare", append([]string{"-rn", python}, args...)...)
+	default:
+		cmd = exec.CommandContext(ctx, python, args...)
+	}
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// prelude limits memory (MB) and cpu time (seconds) before the model code runs
+const localtestPythonPrelude = `import resource
+resource.setrlimit(resource.RLIMIT_AS, (%[1]d * 1024 * 1024, %[1]d * 1024 * 1024))
+resource.setrlimit(resource.RLIMIT_CPU, (%[2]d, %[2]d))
+from typing import *
+import collections, heapq, bisect, math, itertools, functools, string, re
+from collections import *
+from heapq import *
+from bisect import *
+from functools import *
+from itertools import *
+from math import *
+`
+
+// driver calls the method for every example with the time limit in seconds, converting ListNode and TreeNode
+// arguments and results from and to lists
+const localtestPythonDriver = `
+import io as _io, json as _json, signal as _signal, inspect as _inspect, sys as _sys, contextlib as _contextlib
+
+def _split_args(s):
+    parts, depth, cur, quote = [], 0, "", None
+    for ch in s:
+        if quote:
+            if ch == quote:
+                quote = None
+        elif ch in "\"'":
+            quote = ch
+        elif ch in "[{(":
+            depth += 1
+        elif ch in "]})":
+            depth -= 1
+        elif ch == "," and depth == 0:
+            parts.append(cur)
+            cur = ""
+            continue
+        cur += ch
+    parts.append(cur)
+    args = []
+    for part in parts:
+        name, sep, value = part.partition("=")
+        if not sep:
+            name, value = "", name
+        args.append((name.strip(), _parse(value.strip())))
+    return args
+
+def _parse(value):
+    try:
+        return _json.loads(value)
+    except ValueError:
+        return value
+
+def _to_list(values):
+    dummy = ListNode(0)
+    cur = dummy
+    for v in values:
+        cur.next = ListNode(v)
+        cur = cur.next
+    return dummy.next
+
+def _to_tree(values):
+    if not values or values[0] is None:
+        return None
+    root = TreeNode(values[0])
+    queue, i = [root], 1
+    while queue and i < len(values):
+        node = queue.pop(0)
+        for side in ("left", "right"):
+            if i < len(values) and values[i] is not None:
+                child = TreeNode(values[i])
+                setattr(node, side, child)
+                queue.append(child)
+            i += 1
+    return root
+
+def _convert_arg(value, annotation):
+    ann = str(annotation).replace("typing.", "")
+    if "ListNode" in ann and "ListNode" in globals():
+        if ann.startswith("List["):
+            return [_to_list(v) for v in value]
+        return _to_list(value)
+    if "TreeNode" in ann and "TreeNode" in globals():
+        return _to_tree(value)
+    return value
+
+def _normalize(value):
+    if "ListNode" in globals() and isinstance(value, ListNode):
+        out = []
+        while value:
+            out.append(value.val)
+            value = value.next
+        return out
+    if "TreeNode" in globals() and isinstance(value, TreeNode):
+        out, queue = [], [value]
+        while queue:
+            node = queue.pop(0)
+            if node is None:
+                out.append(None)
+                continue
+            out.append(node.val)
+            queue += [node.left, node.right]
+        while out and out[-1] is None:
+            out.pop()
+        return out
+    if isinstance(value, (list, tuple)):
+        return [_normalize(v) for v in value]
+    return value
+
+def _equal(a, b):
+    if isinstance(a, float) or isinstance(b, float):
+        try:
+            return abs(float(a) - float(b)) <= 1e-5
+        except (TypeError, ValueError):
+            return False
+    if isinstance(a, list) and isinstance(b, list):
+        return len(a) == len(b) and all(_equal(x, y) for x, y in zip(a, b))
+    return a == b
+
+def _sorted(value):
+    if isinstance(value, list):
+        return sorted((_sorted(v) for v in value), key=lambda v: _json.dumps(v))
+    return value
+
+def _timeout(signum, frame):
+    raise TimeoutError("Time Limit Exceeded")
+
+def _main():
+    with open(_sys.argv[1]) as f:
+        spec = _json.load(f)
+    _signal.signal(_signal.SIGALRM, _timeout)
+    results = []
+    for example in spec["examples"]:
+        result = {"Input": example["input"], "Expected": example["output"], "Passed": False}
+        try:
+            method = getattr(Solution(), spec["method"])
+            params = list(_inspect.signature(method).parameters.values())
+            args = _split_args(example["input"])
+            values = []
+            for i, param in enumerate(params):
+                match = [v for n, v in args if n == param.name]
+                value = match[0] if match else args[i][1]
+                values.append(_convert_arg(value, param.annotation))
+            _signal.alarm(%d)
+            with _contextlib.redirect_stdout(_io.StringIO()):
+                actual = method(*values)
+            _signal.alarm(0)
+            if _inspect.signature(method).return_annotation in (None, "None"):
+                # in-place problems, like "Do not return anything, modify nums in-place instead"
+                actual = values[0]
+            actual = _normalize(actual)
+            expected = _parse(example["output"])
+            if spec["any_order"]:
+                actual, expected = _sorted(actual), _sorted(expected)
+            result["Actual"] = _json.dumps(actual, separators=(",", ":"))
+            result["Passed"] = _equal(actual, expected) or (isinstance(expected, str) and result["Actual"] == expected)
+        except BaseException as e:
+            _signal.alarm(0)
+            result["Error"] = type(e).__name__ + ": " + str(e)
+        results.append(result)
+    print("` + LOCALTEST_RESULT_PREFIX + `" + _json.dumps(results))
+
+_main()
+`
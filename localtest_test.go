@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const listNodeSnippet = `# Definition for singly-linked list.
+# class ListNode:
+#     def __init__(self, val=0, next=None):
+#         self.val = val
+#         self.next = next
+class Solution:
+    def addTwoNumbers(self, l1: Optional[ListNode], l2: Optional[ListNode]) -> Optional[ListNode]:
+        `
+
+const treeNodeSnippet = `# Definition for a binary tree node.
+# class TreeNode:
+#     def __init__(self, val=0, left=None, right=None):
+#         self.val = val
+#         self.left = left
+#         self.right = right
+class Solution:
+    def maxDepth(self, root: Optional[TreeNode]) -> int:
+        `
+
+func TestRunLocaltest(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not available")
+	}
+	isolation, memoryMb, timeLimit, sanitize := options.LocaltestIsolation, options.LocaltestMemoryMb, options.LocaltestTimeLimit, options.Sanitize
+	t.Cleanup(func() {
+		options.LocaltestIsolation, options.LocaltestMemoryMb, options.LocaltestTimeLimit, options.Sanitize = isolation, memoryMb, timeLimit, sanitize
+	})
+	options.LocaltestIsolation = LOCALTEST_ISOLATION_NONE
+	options.LocaltestMemoryMb = 512
+	options.LocaltestTimeLimit = 5
+	options.Sanitize = false
+
+	tests := []struct {
+		name     string
+		snippet  string
+		code     string
+		spec     localtestSpec
+		expected []bool
+		// substring of the error of the first example
+		expectedError string
+	}{
+		{
+			name: "arguments with strings and lists",
+			code: "class Solution:\n    def f(self, s: str, nums: List[int], k: int) -> int:\n        return len(s) + sum(nums) * k\n",
+			spec: localtestSpec{Method: "f", Examples: []testCase{
+				{Input: `s = "a,b", nums = [1,2], k = 2`, Output: "9"},
+				{Input: `s = "", nums = [], k = 0`, Output: "0"},
+			}},
+			expected: []bool{true, true},
+		},
+		{
+			name: "any order",
+			code: "class Solution:\n    def twoSum(self, nums: List[int], target: int) -> List[int]:\n        return [1, 0]\n",
+			spec: localtestSpec{Method: "twoSum", AnyOrder: true, Examples: []testCase{
+				{Input: "nums = [2,7,11,15], target = 9", Output: "[0,1]"},
+			}},
+			expected: []bool{true},
+		},
+		{
+			name:    "list nodes",
+			snippet: listNodeSnippet,
+			code: "class Solution:\n    def addTwoNumbers(self, l1: Optional[ListNode], l2: Optional[ListNode]) -> Optional[ListNode]:\n" +
+				"        dummy = cur = ListNode()\n        carry = 0\n        while l1 or l2 or carry:\n" +
+				"            carry += (l1.val if l1 else 0) + (l2.val if l2 else 0)\n" +
+				"            cur.next = ListNode(carry % 10)\n            cur, carry = cur.next, carry // 10\n" +
+				"            l1, l2 = l1 and l1.next, l2 and l2.next\n        return dummy.next\n",
+			spec: localtestSpec{Method: "addTwoNumbers", Examples: []testCase{
+				{Input: "l1 = [2,4,3], l2 = [5,6,4]", Output: "[7,0,8]"},
+				{Input: "l1 = [0], l2 = [0]", Output: "[1]"},
+			}},
+			expected: []bool{true, false},
+		},
+		{
+			name:    "tree nodes",
+			snippet: treeNodeSnippet,
+			code: "class Solution:\n    def maxDepth(self, root: Optional[TreeNode]) -> int:\n" +
+				"        return 0 if not root else 1 + max(self.maxDepth(root.left), self.maxDepth(root.right))\n",
+			spec: localtestSpec{Method: "maxDepth", Examples: []testCase{
+				{Input: "root = [3,9,20,null,null,15,7]", Output: "3"},
+				{Input: "root = []", Output: "0"},
+			}},
+			expected: []bool{true, true},
+		},
+		{
+			name: "in-place result",
+			code: "class Solution:\n    def rotate(self, nums: List[int], k: int) -> None:\n" +
+				"        \"\"\"\n        Do not return anything, modify nums in-place instead.\n        \"\"\"\n" +
+				"        k %= len(nums)\n        nums[:] = nums[-k:] + nums[:-k]\n",
+			spec: localtestSpec{Method: "rotate", Examples: []testCase{
+				{Input: "nums = [1,2,3,4,5,6,7], k = 3", Output: "[5,6,7,1,2,3,4]"},
+			}},
+			expected: []bool{true},
+		},
+		{
+			name: "prints do not break results",
+			code: "class Solution:\n    def f(self, x: int) -> int:\n        print('__LOCALTEST_RESULT__[]')\n        return x\n",
+			spec: localtestSpec{Method: "f", Examples: []testCase{
+				{Input: "x = 1", Output: "1"},
+			}},
+			expected: []bool{true},
+		},
+		{
+			name: "exception",
+			code: "class Solution:\n    def f(self, x: int) -> int:\n        return x // 0\n",
+			spec: localtestSpec{Method: "f", Examples: []testCase{
+				{Input: "x = 1", Output: "1"},
+			}},
+			expected:      []bool{false},
+			expectedError: "ZeroDivisionError",
+		},
+		{
+			name: "syntax error fails all examples",
+			code: "class Solution:\n    def f(self, x: int) -> int\n        return x\n",
+			spec: localtestSpec{Method: "f", Examples: []testCase{
+				{Input: "x = 1", Output: "1"},
+				{Input: "x = 2", Output: "2"},
+			}},
+			expected:      []bool{false, false},
+			expectedError: "SyntaxError",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var q Question
+			snippets, err := json.Marshal([]map[string]string{{"LangSlug": "python3", "Code": test.snippet}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = json.Unmarshal(snippets, &q.Data.Question.CodeSnippets)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := runLocaltest(q, Solution{Lang: "python3", TypedCode: test.code}, test.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			passed := []bool{}
+			for _, e := range result.Examples {
+				passed = append(passed, e.Passed)
+			}
+			if !slices.Equal(passed, test.expected) {
+				t.Errorf("expected passed: %v, got: %v in %+v", test.expected, passed, result.Examples)
+			}
+			if test.expectedError != "" && (len(result.Examples) == 0 || !strings.Contains(result.Examples[0].Error, test.expectedError)) {
+				t.Errorf("expected error with %q, got: %+v", test.expectedError, result.Examples)
+			}
+		})
+	}
+}
+
+func TestLocaltestCommand(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not available")
+	}
+	isolation := options.LocaltestIsolation
+	t.Cleanup(func() { options.LocaltestIsolation = isolation })
+
+	tests := []struct {
+		isolation    string
+		expectedTool string
+		expectedArgs []string
+	}{
+		{isolation: LOCALTEST_ISOLATION_BWRAP, expectedTool: "bwrap", expectedArgs: []string{"--unshare-all", "--ro-bind"}},
+		{isolation: LOCALTEST_ISOLATION_UNSHARE, expectedTool: "unshare", expectedArgs: []string{"-rn"}},
+		{isolation: LOCALTEST_ISOLATION_NONE, expectedTool: "python3"},
+	}
+	for _, test := range tests {
+		t.Run(test.isolation, func(t *testing.T) {
+			options.LocaltestIsolation = test.isolation
+			dir := t.TempDir()
+			cmd, err := localtestCommand(context.Background(), dir, "solution.py", "spec.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tool := filepath.Base(cmd.Args[0])
+			if test.expectedTool == "python3" {
+				// the resolved interpreter may be python3.x
+				tool = tool[:min(len(tool), len("python3"))]
+			}
+			if tool != test.expectedTool {
+				t.Errorf("expected tool: %s, got: %s", test.expectedTool, cmd.Args[0])
+			}
+			for _, arg := range append(test.expectedArgs, "solution.py", "spec.json") {
+				if !slices.Contains(cmd.Args, arg) {
+					t.Errorf("expected %s in args: %v", arg, cmd.Args)
+				}
+			}
+			if cmd.Dir != dir {
+				t.Errorf("expected dir: %s, got: %s", dir, cmd.Dir)
+			}
+		})
+	}
+}
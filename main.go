@@ -3,6 +3,7 @@
 package main
 
 import (
+	"io"
 	"os"
 	"runtime/debug"
 	"time"
@@ -18,35 +19,90 @@ var options struct {
 	Force                    bool
 	Verbose                  int
 	Dir                      string
+	Site                     string
 	DryRun                   bool `mapstructure:"dry_run"`
 	Slugs                    bool
 	DetectApproxCreationDate bool `mapstructure:"detect_approx_creation_date"`
 	SkipPaid                 bool `mapstructure:"skip_paid"`
 	SkipAuthCheck            bool `mapstructure:"skip_auth_check"`
 	Update                   bool
+	Batch                    bool
+	Collect                  bool
+	Stream                   bool
+	Progress                 bool
+	BatchPollInterval        time.Duration `mapstructure:"batch_poll_interval"`
+	CheckInterval            time.Duration `mapstructure:"check_interval"`
 	Language                 string
 	Model                    string
+	FewShot                  []string `mapstructure:"few_shot"`
+	Template                 string
 	ModelVendor              string  `mapstructure:"model_vendor"`
+	ContentLang              string  `mapstructure:"content_lang"`
 	Retries                  int
 	PromptParallelism        int     `mapstructure:"prompt_parallelism"`
 	PromptRateLimit          float64 `mapstructure:"prompt_rate_limit"`
 	PromptRateBurst          int     `mapstructure:"prompt_rate_burst"`
-	SubmitRateLimit          float64 `mapstructure:"submit_rate_limit"`
-	SubmitRateBurst          int     `mapstructure:"submit_rate_burst"`
+	LeetcodeRateLimit        float64 `mapstructure:"leetcode_rate_limit"`
+	LeetcodeRateBurst        int     `mapstructure:"leetcode_rate_burst"`
+	LeetcodeLimiterState     string  `mapstructure:"leetcode_limiter_state"`
 	CheckRetries             int     `mapstructure:"check_retries"`
 	SubmitRetries            int     `mapstructure:"submit_retries"`
 	AddMetadataComment       bool    `mapstructure:"add_metadata_comment"`
+	DownloadImages           bool    `mapstructure:"download_images"`
+	PromptImages             bool    `mapstructure:"prompt_images"`
+	TemplatesDir             string  `mapstructure:"templates_dir"`
+	OnRejection              string  `mapstructure:"on_rejection"`
+	BatchDir                 string  `mapstructure:"batch_dir"`
+	CacheDir                 string  `mapstructure:"cache_dir"`
+	NoCache                  bool    `mapstructure:"no_cache"`
+	SummaryJson              string  `mapstructure:"summary_json"`
+	JournalDir               string  `mapstructure:"journal_dir"`
+	LogFormat                string  `mapstructure:"log_format"`
+	LogFile                  string  `mapstructure:"log_file"`
+	Sanitize                 bool
+	SkipFailedCheck          bool   `mapstructure:"skip_failed_check"`
+	LocaltestMemoryMb        int    `mapstructure:"localtest_memory_mb"`
+	LocaltestTimeLimit       int    `mapstructure:"localtest_time_limit"`
+	LocaltestIsolation       string `mapstructure:"localtest_isolation"`
 
 	// options below usually set in config file
 	ChatgptApiKey         string `mapstructure:"chatgpt_api_key"`
 	GeminiProjectId       string `mapstructure:"gemini_project_id"`
 	GeminiRegion          string `mapstructure:"gemini_region"`
 	GeminiCredentialsFile string `mapstructure:"gemini_credentials_file"`
+	GeminiBackend         string `mapstructure:"gemini_backend"`
+	GeminiApiKey          string `mapstructure:"gemini_api_key"`
 	ClaudeApiKey          string `mapstructure:"claude_api_key"`
 	DeepseekApiKey        string `mapstructure:"deepseek_api_key"`
 	XaiApiKey             string `mapstructure:"xai_api_key"`
+	MistralApiKey         string `mapstructure:"mistral_api_key"`
+	MistralBaseUrl        string `mapstructure:"mistral_base_url"`
+	QwenApiKey            string `mapstructure:"qwen_api_key"`
+	QwenBaseUrl           string `mapstructure:"qwen_base_url"`
+	MoonshotApiKey        string `mapstructure:"moonshot_api_key"`
+	MoonshotBaseUrl       string `mapstructure:"moonshot_base_url"`
+	AzureOpenaiApiKey     string `mapstructure:"azure_openai_api_key"`
+	AzureOpenaiEndpoint   string `mapstructure:"azure_openai_endpoint"`
+	AzureOpenaiApiVersion string `mapstructure:"azure_openai_api_version"`
+	// model names to azure deployment names
+	AzureOpenaiDeployments map[string]string `mapstructure:"azure_openai_deployments"`
+
+	// leetcode session sources, in order of priority
+	LeetcodeSession   string `mapstructure:"leetcode_session"`
+	LeetcodeCsrfToken string `mapstructure:"leetcode_csrftoken"`
+	CookiesFile       string `mapstructure:"cookies_file"`
+	Browser           string
+	BrowserProfile    string `mapstructure:"browser_profile"`
 
 	PromptTemplate string `mapstructure:"prompt_template"`
+	// system message sent with prompt_template and prompt_templates, named templates define their own
+	SystemPrompt string `mapstructure:"system_prompt"`
+	// per-category templates override prompt_template, e.g. for shell or database problems
+	PromptTemplates map[string]string `mapstructure:"prompt_templates"`
+	// per-language sanitizer chains, override the default chain
+	Sanitizers map[string][]string
+	// per-vendor prompt limits by vendor name, override prompt_* options
+	VendorLimits map[string]VendorLimit `mapstructure:"vendor_limits"`
 }
 
 func initConfig() {
@@ -65,6 +121,38 @@ func initConfig() {
 	}
 }
 
+const (
+	LOG_FORMAT_CONSOLE = "console"
+	LOG_FORMAT_JSON    = "json"
+)
+
+// initLogging replaces the console logger set up before flags are parsed
+func initLogging() {
+	if options.LogFormat != LOG_FORMAT_CONSOLE && options.LogFormat != LOG_FORMAT_JSON {
+		log.Fatal().Msgf("unknown log format %s, expected %s or %s", options.LogFormat, LOG_FORMAT_CONSOLE, LOG_FORMAT_JSON)
+	}
+	writers := []io.Writer{newLogWriter(os.Stderr, false)}
+	if options.LogFile != "" {
+		file, err := os.OpenFile(options.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open the log file")
+		}
+		writers = append(writers, newLogWriter(file, true))
+	}
+	log.Logger = zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp().Logger()
+}
+
+func newLogWriter(out io.Writer, noColor bool) io.Writer {
+	if options.LogFormat == LOG_FORMAT_JSON {
+		return out
+	}
+	consoleWriter := zerolog.NewConsoleWriter()
+	consoleWriter.TimeFormat = time.DateTime
+	consoleWriter.Out = out
+	consoleWriter.NoColor = noColor
+	return consoleWriter
+}
+
 func initVerbosity() {
 	if options.Verbose >= 2 {
 		zerolog.SetGlobalLevel(zerolog.TraceLevel)
@@ -92,7 +180,7 @@ func getVersion() string {
 }
 
 func main() {
-	cobra.OnInitialize(initConfig, initVerbosity)
+	cobra.OnInitialize(initConfig, initLogging, initVerbosity, initSite)
 	consoleWriter := zerolog.NewConsoleWriter()
 	consoleWriter.TimeFormat = time.DateTime
 	consoleWriter.Out = os.Stderr
@@ -106,7 +194,19 @@ func main() {
 	rootCmd.PersistentFlags().BoolP("force", "f", false, "be forceful: download already downloaded, submit already submitted etc.")
 	rootCmd.PersistentFlags().StringP("dir", "D", "problems", "")
 	rootCmd.PersistentFlags().BoolP("dry_run", "d", false, "do not make any changes to problem files")
+	rootCmd.PersistentFlags().String("site", DEFAULT_SITE, "leetcode site (leetcode.com|leetcode.cn)")
 	rootCmd.PersistentFlags().CountP("verbose", "v", "increase verbosity level. Use -v for troubleshooting, -vv for advanced debugging")
+	rootCmd.PersistentFlags().Float64("leetcode_rate_limit", 0.2, "leetcode request rate limit in requests/second, shared by download and submit (0 means no limit)")
+	rootCmd.PersistentFlags().Int("leetcode_rate_burst", 2, "leetcode rate limiter burst size")
+	rootCmd.PersistentFlags().String("cookies_file", "", "read leetcode session from a cookies.txt file (Netscape format)")
+	rootCmd.PersistentFlags().String("browser", "firefox", "read leetcode session from the browser (firefox|chrome|...)")
+	rootCmd.PersistentFlags().String("browser_profile", "", "browser profile to read leetcode session from (default profile if empty)")
+	rootCmd.PersistentFlags().Bool("progress", false, "log progress with ETA and counts per model after every problem, print a summary table to stderr at the end (prompt|submit|download)")
+	rootCmd.PersistentFlags().String("summary_json", "", "write the summary of the run as json into the file, - for stdout (prompt|submit|download)")
+	rootCmd.PersistentFlags().String("journal_dir", "journals", "directory of run journals, jsonl files with an event per prompt or submission (empty to disable)")
+	rootCmd.PersistentFlags().String("log_format", LOG_FORMAT_CONSOLE, "log format (console|json)")
+	rootCmd.PersistentFlags().String("log_file", "", "also write logs into the file, appending to it")
+	rootCmd.PersistentFlags().String("leetcode_limiter_state", "", "file to share leetcode rate limiter state between processes (default is in the temp dir)")
 	err := viper.BindPFlags(rootCmd.PersistentFlags())
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to bind flags. This is a bug")
@@ -122,24 +222,27 @@ func main() {
 			viper.BindPFlag("skip_auth_check", cmd.Flags().Lookup("skip_auth_check"))
 			viper.BindPFlag("detect_approx_creation_date", cmd.Flags().Lookup("detect_approx_creation_date"))
 			viper.BindPFlag("update", cmd.Flags().Lookup("update"))
+			viper.BindPFlag("download_images", cmd.Flags().Lookup("download_images"))
 			viper.Unmarshal(&options)
 			download(cmd.Flag("category").Value.String(), args)
 		},
 	}
-	cmdDownload.Flags().StringP("category", "c", "algorithms", "problem type")
+	cmdDownload.Flags().StringP("category", "c", "algorithms", "problem category (all|algorithms|database|shell|concurrency|pandas|javascript)")
 	cmdDownload.Flags().BoolP("slugs", "s", false, "list available problem slugs without downloading")
 	cmdDownload.Flags().BoolP("skip_paid", "P", false, "skip paid problems")
 	cmdDownload.Flags().BoolP("skip_auth_check", "A", false, "allow anonymous download (disable username check)")
 	cmdDownload.Flags().BoolP("detect_approx_creation_date", "C", true, "determine approximate creation date for each problem based on user-generated content")
 	cmdDownload.Flags().BoolP("update", "u", false, "update existing problems with the new question data (useful for updating problem stats)")
+	cmdDownload.Flags().Bool("download_images", true, "download images referenced in problems, so they can be attached to prompts")
 
 	cmdList := &cobra.Command{
 		Use:   "list",
 		Short: "List problems info using jq",
 		Run: func(cmd *cobra.Command, args []string) {
-			list(args, cmd.Flag("where").Value.String(), cmd.Flag("order_by").Value.String(), cmd.Flag("print").Value.String(), cmd.Flag("header").Value.String() == "true")
+			list(args, cmd.Flag("category").Value.String(), cmd.Flag("where").Value.String(), cmd.Flag("order_by").Value.String(), cmd.Flag("print").Value.String(), cmd.Flag("header").Value.String() == "true")
 		},
 	}
+	cmdList.Flags().StringP("category", "c", "", "list only problems of the category (algorithms|database|shell|concurrency|pandas|javascript)")
 	cmdList.Flags().StringP("where", "w", "", "filter problems by where clause (jq expression)")
 	cmdList.Flags().StringP("order_by", "o", "", "order by jq expression")
 	cmdList.Flags().StringP("print", "p", ".", "print fields (jq expression)")
@@ -155,17 +258,42 @@ func main() {
 			viper.BindPFlag("prompt_parallelism", cmd.Flags().Lookup("prompt_parallelism"))
 			viper.BindPFlag("prompt_rate_limit", cmd.Flags().Lookup("prompt_rate_limit"))
 			viper.BindPFlag("prompt_rate_burst", cmd.Flags().Lookup("prompt_rate_burst"))
+			viper.BindPFlag("prompt_images", cmd.Flags().Lookup("prompt_images"))
+			viper.BindPFlag("content_lang", cmd.Flags().Lookup("content_lang"))
+			viper.BindPFlag("template", cmd.Flags().Lookup("template"))
+			viper.BindPFlag("templates_dir", cmd.Flags().Lookup("templates_dir"))
+			viper.BindPFlag("few_shot", cmd.Flags().Lookup("few_shot"))
+			viper.BindPFlag("stream", cmd.Flags().Lookup("stream"))
+			viper.BindPFlag("no_cache", cmd.Flags().Lookup("no_cache"))
+			viper.BindPFlag("cache_dir", cmd.Flags().Lookup("cache_dir"))
+			viper.BindPFlag("batch", cmd.Flags().Lookup("batch"))
+			viper.BindPFlag("collect", cmd.Flags().Lookup("collect"))
+			viper.BindPFlag("batch_dir", cmd.Flags().Lookup("batch_dir"))
+			viper.BindPFlag("batch_poll_interval", cmd.Flags().Lookup("batch_poll_interval"))
 			viper.Unmarshal(&options)
-			prompt(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String(), cmd.Flag("model_vendor").Value.String())
+			models, _ := cmd.Flags().GetStringArray("model")
+			prompt(args, cmd.Flag("language").Value.String(), models, cmd.Flag("model_vendor").Value.String())
 		},
 	}
 	cmdPrompt.PersistentFlags().StringP("language", "l", "python3", "programming language")
-	cmdPrompt.PersistentFlags().StringP("model", "m", "", "model name to use")
-	cmdPrompt.PersistentFlags().String("model_vendor", "", "model vendor override (openai|vertexai|anthropic|deepseek|xai)")
+	cmdPrompt.PersistentFlags().StringArrayP("model", "m", nil, "model name to use, repeat to prompt several models in one run")
+	cmdPrompt.PersistentFlags().String("model_vendor", "", "model vendor override for all models (openai|vertexai|anthropic|deepseek|xai|mistral|qwen|moonshot|azure)")
 	cmdPrompt.PersistentFlags().IntP("retries", "r", 2, "number of retries")
-	cmdPrompt.PersistentFlags().Int("prompt_parallelism", 8, "number of prompt workers")
-	cmdPrompt.PersistentFlags().Float64("prompt_rate_limit", 1.0/30.0, "prompt request rate limit in requests/second")
+	cmdPrompt.PersistentFlags().Int("prompt_parallelism", 8, "number of prompt workers per vendor")
+	cmdPrompt.PersistentFlags().Float64("prompt_rate_limit", 1.0/30.0, "prompt request rate limit of each vendor in requests/second")
 	cmdPrompt.PersistentFlags().Int("prompt_rate_burst", 2, "prompt rate limiter burst size")
+	cmdPrompt.PersistentFlags().String("content_lang", "", "natural language of the problem statement, e.g. zh or es (problem must have the translation)")
+	cmdPrompt.PersistentFlags().StringP("template", "t", "", "named prompt template from the templates dir (prompt_template from the config if empty)")
+	cmdPrompt.PersistentFlags().String("templates_dir", "templates", "directory with named prompt templates")
+	cmdPrompt.PersistentFlags().StringSlice("few_shot", nil, "problem files with accepted solutions to show to the model as examples before the question")
+	cmdPrompt.PersistentFlags().Bool("prompt_images", true, "attach problem images to prompts for models which support them (openai|vertexai|anthropic)")
+	cmdPrompt.PersistentFlags().Bool("no_cache", false, "always call the model, neither reading nor writing the response cache")
+	cmdPrompt.PersistentFlags().String("cache_dir", ".cache/responses", "directory of the response cache")
+	cmdPrompt.PersistentFlags().Bool("stream", true, "use streaming apis, measuring time to first token and tokens per second")
+	cmdPrompt.PersistentFlags().Bool("batch", false, "submit prompts of all unsolved problems as one batch job at the batch price (openai|anthropic)")
+	cmdPrompt.PersistentFlags().Bool("collect", false, "wait for submitted batches to end and save their results. Args are batch files, all batches in the batch dir by default")
+	cmdPrompt.PersistentFlags().String("batch_dir", "batches", "directory with submitted batches")
+	cmdPrompt.PersistentFlags().Duration("batch_poll_interval", time.Minute, "interval between checks of running batches, 0 to check once")
 
 	cmdSubmit := &cobra.Command{
 		Use:   "submit",
@@ -174,9 +302,21 @@ func main() {
 			viper.BindPFlag("language", cmd.Flags().Lookup("language"))
 			viper.BindPFlag("submit_retries", cmd.Flags().Lookup("submit_retries"))
 			viper.BindPFlag("check_retries", cmd.Flags().Lookup("check_retries"))
-			viper.BindPFlag("submit_rate_limit", cmd.Flags().Lookup("submit_rate_limit"))
-			viper.BindPFlag("submit_rate_burst", cmd.Flags().Lookup("submit_rate_burst"))
+			viper.BindPFlag("check_interval", cmd.Flags().Lookup("check_interval"))
 			viper.BindPFlag("add_metadata_comment", cmd.Flags().Lookup("add_metadata_comment"))
+			viper.BindPFlag("on_rejection", cmd.Flags().Lookup("on_rejection"))
+			viper.BindPFlag("sanitize", cmd.Flags().Lookup("sanitize"))
+			viper.BindPFlag("skip_failed_check", cmd.Flags().Lookup("skip_failed_check"))
+			viper.BindPFlag("content_lang", cmd.Flags().Lookup("content_lang"))
+			viper.BindPFlag("template", cmd.Flags().Lookup("template"))
+			viper.BindPFlag("few_shot", cmd.Flags().Lookup("few_shot"))
+			// deprecated aliases of the shared leetcode limiter flags
+			if cmd.Flags().Changed("submit_rate_limit") {
+				viper.Set("leetcode_rate_limit", cmd.Flag("submit_rate_limit").Value.String())
+			}
+			if cmd.Flags().Changed("submit_rate_burst") {
+				viper.Set("leetcode_rate_burst", cmd.Flag("submit_rate_burst").Value.String())
+			}
 			viper.Unmarshal(&options)
 			submit(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String())
 		},
@@ -184,10 +324,64 @@ func main() {
 	cmdSubmit.PersistentFlags().StringP("language", "l", "python3", "programming language")
 	cmdSubmit.Flags().Int("submit_retries", 2, "number of retries")
 	cmdSubmit.Flags().Int("check_retries", 5, "number of retries")
-	cmdSubmit.Flags().Float64("submit_rate_limit", 0.1, "submit/check request rate limit in requests/second")
-	cmdSubmit.Flags().Int("submit_rate_burst", 1, "submit/check rate limiter burst size")
+	cmdSubmit.Flags().Duration("check_interval", 5*time.Second, "interval between checks of the submission status, independent of the leetcode rate limit")
+	cmdSubmit.Flags().Float64("submit_rate_limit", 0.2, "submit/check request rate limit in requests/second")
+	cmdSubmit.Flags().Int("submit_rate_burst", 2, "submit/check rate limiter burst size")
+	cmdSubmit.Flags().MarkDeprecated("submit_rate_limit", "use --leetcode_rate_limit")
+	cmdSubmit.Flags().MarkDeprecated("submit_rate_burst", "use --leetcode_rate_burst")
 	cmdSubmit.Flags().Bool("add_metadata_comment", true, "add a comment with metadata to the submitted code")
+	cmdSubmit.Flags().String("on_rejection", ON_REJECTION_STRIP, "when leetcode rejects the code with 403, retry once: strip (without the comment), neutral (comment without the model) or none")
+	cmdSubmit.Flags().Bool("skip_failed_check", true, "skip solutions which failed the local check (see the check command)")
+	cmdSubmit.Flags().Bool("sanitize", true, "fix common issues in the code before submission, like main() functions or package clauses (see sanitizers in the config)")
 	cmdSubmit.PersistentFlags().StringP("model", "m", "", "model name to use")
+	cmdSubmit.PersistentFlags().StringP("template", "t", "", "submit solutions prompted with this named template")
+	cmdSubmit.PersistentFlags().StringSlice("few_shot", nil, "submit solutions prompted with these few-shot problem files")
+	cmdSubmit.PersistentFlags().String("content_lang", "", "submit solutions prompted with the problem statement in this natural language")
+
+	cmdCheck := &cobra.Command{
+		Use:   "check",
+		Short: "Check solutions for syntax and compile errors with local toolchains (python3|golang|c|cpp|java|rust)",
+		Run: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("language", cmd.Flags().Lookup("language"))
+			viper.BindPFlag("content_lang", cmd.Flags().Lookup("content_lang"))
+			viper.BindPFlag("template", cmd.Flags().Lookup("template"))
+			viper.BindPFlag("few_shot", cmd.Flags().Lookup("few_shot"))
+			viper.BindPFlag("sanitize", cmd.Flags().Lookup("sanitize"))
+			viper.Unmarshal(&options)
+			check(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String())
+		},
+	}
+	cmdCheck.Flags().StringP("language", "l", "python3", "programming language")
+	cmdCheck.Flags().StringP("model", "m", "", "model name to use")
+	cmdCheck.Flags().StringP("template", "t", "", "check solutions prompted with this named template")
+	cmdCheck.Flags().StringSlice("few_shot", nil, "check solutions prompted with these few-shot problem files")
+	cmdCheck.Flags().String("content_lang", "", "check solutions prompted with the problem statement in this natural language")
+	cmdCheck.Flags().Bool("sanitize", true, "check the code sanitized as for submission")
+
+	cmdLocaltest := &cobra.Command{
+		Use:   "localtest",
+		Short: "Run solutions on examples from problem statements locally (python3 only)",
+		Run: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("content_lang", cmd.Flags().Lookup("content_lang"))
+			viper.BindPFlag("template", cmd.Flags().Lookup("template"))
+			viper.BindPFlag("few_shot", cmd.Flags().Lookup("few_shot"))
+			viper.BindPFlag("sanitize", cmd.Flags().Lookup("sanitize"))
+			viper.BindPFlag("localtest_memory_mb", cmd.Flags().Lookup("localtest_memory_mb"))
+			viper.BindPFlag("localtest_time_limit", cmd.Flags().Lookup("localtest_time_limit"))
+			viper.BindPFlag("localtest_isolation", cmd.Flags().Lookup("localtest_isolation"))
+			viper.Unmarshal(&options)
+			localtest(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String())
+		},
+	}
+	cmdLocaltest.Flags().StringP("language", "l", "python3", "programming language")
+	cmdLocaltest.Flags().StringP("model", "m", "", "model name to use")
+	cmdLocaltest.Flags().StringP("template", "t", "", "test solutions prompted with this named template")
+	cmdLocaltest.Flags().StringSlice("few_shot", nil, "test solutions prompted with these few-shot problem files")
+	cmdLocaltest.Flags().String("content_lang", "", "test solutions prompted with the problem statement in this natural language")
+	cmdLocaltest.Flags().Bool("sanitize", true, "test the code sanitized as for submission")
+	cmdLocaltest.Flags().Int("localtest_memory_mb", 512, "memory limit of the test process in megabytes")
+	cmdLocaltest.Flags().Int("localtest_time_limit", 5, "time limit of an example in seconds")
+	cmdLocaltest.Flags().String("localtest_isolation", LOCALTEST_ISOLATION_BWRAP, "isolation of the model code: bwrap (read-only filesystem, no network), unshare (no network only) or none")
 
 	cmdFix := &cobra.Command{
 		Use:   "fix",
@@ -197,7 +391,54 @@ func main() {
 		},
 	}
 
-	rootCmd.AddCommand(cmdDownload, cmdList, cmdPrompt, cmdSubmit, cmdFix)
+	cmdTranslate := &cobra.Command{
+		Use:   "translate",
+		Short: "Add translations of problem statements, for prompting in other natural languages",
+		Run: func(cmd *cobra.Command, args []string) {
+			translate(args, cmd.Flag("content_lang").Value.String(), cmd.Flag("source").Value.String())
+		},
+	}
+	cmdTranslate.Flags().StringP("content_lang", "L", "", "language code of the translation, e.g. zh or es")
+	cmdTranslate.Flags().String("source", "", "leetcode.cn (zh only, requires --site leetcode.cn) or a directory with <slug>.html|md|txt files")
+
+	cmdReport := &cobra.Command{
+		Use:   "report",
+		Short: "Print reports on solutions and submissions",
+	}
+	cmdReportFailures := &cobra.Command{
+		Use:   "failures",
+		Short: "Count non-accepted solutions by failure class per model, language and difficulty",
+		Run: func(cmd *cobra.Command, args []string) {
+			reportFailures(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String())
+		},
+	}
+	cmdReportFailures.Flags().StringP("language", "l", "", "only solutions in this language, all by default")
+	cmdReportFailures.Flags().StringP("model", "m", "", "only solutions of this model or result key, all by default")
+	cmdReportLatency := &cobra.Command{
+		Use:   "latency",
+		Short: "Print median latency, time to first token and tokens per second per model and language",
+		Run: func(cmd *cobra.Command, args []string) {
+			reportLatency(args, cmd.Flag("language").Value.String(), cmd.Flag("model").Value.String())
+		},
+	}
+	cmdReportLatency.Flags().StringP("language", "l", "", "only solutions in this language, all by default")
+	cmdReportLatency.Flags().StringP("model", "m", "", "only solutions of this model or result key, all by default")
+	cmdReport.AddCommand(cmdReportFailures, cmdReportLatency)
+
+	cmdLogin := &cobra.Command{
+		Use:   "login",
+		Short: "Manage leetcode session",
+	}
+	cmdLoginStatus := &cobra.Command{
+		Use:   "status",
+		Short: "Show the active session source, session expiration and the signed in user",
+		Run: func(cmd *cobra.Command, args []string) {
+			loginStatus()
+		},
+	}
+	cmdLogin.AddCommand(cmdLoginStatus)
+
+	rootCmd.AddCommand(cmdDownload, cmdList, cmdPrompt, cmdCheck, cmdLocaltest, cmdSubmit, cmdFix, cmdTranslate, cmdReport, cmdLogin)
 
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
@@ -0,0 +1,70 @@
+package leetgptsolver
+
+import (
+	"strings"
+)
+
+// UncommentDefinitions returns definitions of types leetcode provides for the solution, like ListNode and TreeNode.
+// Snippets show them commented out after a "Definition for ..." line, either as line comments
+// or inside a /* */ block or a python docstring
+func UncommentDefinitions(snippet string) string {
+	definitions := []string{}
+	blockEnd := ""      // end of the block comment the current line is in
+	collecting := false // inside a definition
+	raw := false        // definition lines are not prefixed with comment markers
+	for _, line := range strings.Split(snippet, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if blockEnd != "" && strings.HasPrefix(trimmed, blockEnd) {
+			blockEnd, collecting = "", false
+			continue
+		}
+		if blockEnd == "" {
+			switch {
+			case strings.HasPrefix(trimmed, "/*") && !strings.Contains(trimmed, "*/"):
+				blockEnd = "*/"
+				continue
+			case trimmed == `"""`:
+				blockEnd = `"""`
+				continue
+			}
+		}
+
+		if strings.Contains(trimmed, "Definition for") {
+			collecting = true
+			// in a block without "*" prefixes, definitions are written as is
+			raw = blockEnd != "" && !strings.HasPrefix(trimmed, "*")
+			continue
+		}
+		if !collecting {
+			continue
+		}
+		if raw {
+			definitions = append(definitions, line)
+			continue
+		}
+		uncommented, ok := uncommentLine(line)
+		if !ok {
+			collecting = false
+			continue
+		}
+		definitions = append(definitions, uncommented)
+	}
+
+	if len(definitions) == 0 {
+		return ""
+	}
+	return strings.TrimRight(strings.Join(definitions, "\n"), "\n") + "\n"
+}
+
+// uncommentLine strips the comment marker and one space after it, keeping the indentation of the code
+func uncommentLine(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, marker := range []string{"//", "#", "*"} {
+		if strings.HasPrefix(trimmed, marker) && !strings.HasPrefix(trimmed, "#[") {
+			rest := strings.TrimPrefix(trimmed, marker)
+			return strings.TrimPrefix(rest, " "), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+package leetgptsolver
+
+import (
+	"testing"
+)
+
+func TestUncommentDefinitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippet  string
+		expected string
+	}{
+		{
+			name:     "no definitions",
+			snippet:  "class Solution:\n    def twoSum(self, nums: List[int], target: int) -> List[int]:\n        ",
+			expected: "",
+		},
+		{
+			name: "python line comments",
+			snippet: "# Definition for singly-linked list.\n" +
+				"# class ListNode:\n" +
+				"#     def __init__(self, val=0, next=None):\n" +
+				"#         self.val = val\n" +
+				"class Solution:\n",
+			expected: "class ListNode:\n    def __init__(self, val=0, next=None):\n        self.val = val\n",
+		},
+		{
+			name: "python docstring",
+			snippet: "\"\"\"\n" +
+				"# Definition for a Node.\n" +
+				"class Node:\n" +
+				"    def __init__(self, val = 0):\n" +
+				"        self.val = val\n" +
+				"\"\"\"\n" +
+				"class Solution:\n",
+			expected: "class Node:\n    def __init__(self, val = 0):\n        self.val = val\n",
+		},
+		{
+			name: "cpp block comment",
+			snippet: "/**\n" +
+				" * Definition for singly-linked list.\n" +
+				" * struct ListNode {\n" +
+				" *     int val;\n" +
+				" *     ListNode *next;\n" +
+				" * };\n" +
+				" */\n" +
+				"class Solution {\n",
+			expected: "struct ListNode {\n    int val;\n    ListNode *next;\n};\n",
+		},
+		{
+			name: "java raw block",
+			snippet: "/*\n" +
+				"// Definition for a Node.\n" +
+				"class Node {\n" +
+				"    public int val;\n" +
+				"}\n" +
+				"*/\n" +
+				"\n" +
+				"class Solution {\n",
+			expected: "class Node {\n    public int val;\n}\n",
+		},
+		{
+			name: "rust line comments with empty comment lines",
+			snippet: "// Definition for singly-linked list.\n" +
+				"// #[derive(PartialEq, Eq, Clone, Debug)]\n" +
+				"// pub struct ListNode {\n" +
+				"//   pub val: i32,\n" +
+				"// }\n" +
+				"//\n" +
+				"// impl ListNode {\n" +
+				"// }\n" +
+				"impl Solution {\n",
+			expected: "#[derive(PartialEq, Eq, Clone, Debug)]\npub struct ListNode {\n  pub val: i32,\n}\n\nimpl ListNode {\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			definitions := UncommentDefinitions(test.snippet)
+			if definitions != test.expected {
+				t.Errorf("expected definitions: %q, got: %q", test.expected, definitions)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package leetgptsolver
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Example is a sample test case from a problem statement
+type Example struct {
+	Input       string
+	Output      string
+	Explanation string
+	// whole example as it appears in the statement
+	Text string
+}
+
+var exampleHeaderRe = regexp.MustCompile(`(?m)^[ \t]*Example\s*\d*\s*:[ \t]*`)
+var exampleEndRe = regexp.MustCompile(`(?m)^[ \t]*(Constraints|Note|Follow[ -]up)\b`)
+var exampleFieldRe = regexp.MustCompile(`(?m)^[ \t]*(Input|Output|Explanation)\s*:[ \t]*`)
+
+// ParseExamples extracts examples from a problem statement converted to plain text.
+// Examples start with "Example N:" lines and end at the next example or at the constraints
+func ParseExamples(text string) []Example {
+	headers := exampleHeaderRe.FindAllStringIndex(text, -1)
+	if len(headers) == 0 {
+		return nil
+	}
+
+	examples := []Example{}
+	for i, h := range headers {
+		end := len(text)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		} else if m := exampleEndRe.FindStringIndex(text[h[1]:]); m != nil {
+			end = h[1] + m[0]
+		}
+		body := strings.TrimSpace(text[h[1]:end])
+		if body == "" {
+			continue
+		}
+		examples = append(examples, parseExample(body))
+	}
+
+	return examples
+}
+
+func parseExample(body string) Example {
+	e := Example{Text: body}
+	fields := exampleFieldRe.FindAllStringSubmatchIndex(body, -1)
+	for i, f := range fields {
+		end := len(body)
+		if i+1 < len(fields) {
+			end = fields[i+1][0]
+		}
+		value := strings.TrimSpace(body[f[1]:end])
+		switch body[f[2]:f[3]] {
+		case "Input":
+			e.Input = value
+		case "Output":
+			e.Output = value
+		case "Explanation":
+			e.Explanation = value
+		}
+	}
+	return e
+}
@@ -0,0 +1,82 @@
+package leetgptsolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []Example
+	}{
+		{
+			name:     "no examples",
+			text:     "Given an array of integers, return the sum.\nConstraints:\n1 <= n <= 10",
+			expected: nil,
+		},
+		{
+			name: "two examples with constraints",
+			text: "Given an array of integers nums and an integer target, return indices.\n" +
+				"Example 1:\n" +
+				"Input: nums = [2,7,11,15], target = 9\n" +
+				"Output: [0,1]\n" +
+				"Explanation: Because nums[0] + nums[1] == 9, we return [0, 1].\n" +
+				"Example 2:\n" +
+				"Input: nums = [3,2,4], target = 6\n" +
+				"Output: [1,2]\n" +
+				"Constraints:\n" +
+				"2 <= nums.length <= 10^4",
+			expected: []Example{
+				{
+					Input:       "nums = [2,7,11,15], target = 9",
+					Output:      "[0,1]",
+					Explanation: "Because nums[0] + nums[1] == 9, we return [0, 1].",
+					Text:        "Input: nums = [2,7,11,15], target = 9\nOutput: [0,1]\nExplanation: Because nums[0] + nums[1] == 9, we return [0, 1].",
+				},
+				{
+					Input:  "nums = [3,2,4], target = 6",
+					Output: "[1,2]",
+					Text:   "Input: nums = [3,2,4], target = 6\nOutput: [1,2]",
+				},
+			},
+		},
+		{
+			name: "multiline explanation and follow-up",
+			text: "Example:\n" +
+				" Input: s = \"abc\"\n" +
+				" Output: 3\n" +
+				" Explanation: first line\n" +
+				"second line\n" +
+				"Follow-up: can you do it in O(1) memory?",
+			expected: []Example{
+				{
+					Input:       `s = "abc"`,
+					Output:      "3",
+					Explanation: "first line\nsecond line",
+					Text:        "Input: s = \"abc\"\n Output: 3\n Explanation: first line\nsecond line",
+				},
+			},
+		},
+		{
+			name: "example without fields",
+			text: "Example 1:\n" +
+				"see the picture\n" +
+				"Constraints:\n" +
+				"none",
+			expected: []Example{
+				{Text: "see the picture"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			examples := ParseExamples(test.text)
+			if !reflect.DeepEqual(examples, test.expected) {
+				t.Errorf("expected examples: %#v, got: %#v", test.expected, examples)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package leetgptsolver
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// strategies used to extract code from model answers
+const (
+	// the only fenced block in the answer
+	EXTRACT_FENCED = "fenced"
+	// the best scored of several fenced blocks
+	EXTRACT_FENCED_SCORED = "fenced_scored"
+	// fenced block without the closing fence, e.g. the answer was cut off
+	EXTRACT_UNTERMINATED = "unterminated_fence"
+	// <pre> or <code> html block
+	EXTRACT_HTML = "html_code"
+	// no blocks found, the answer is taken as is
+	EXTRACT_RAW = "raw"
+	// the answer is empty
+	EXTRACT_NONE = "none"
+)
+
+// code block tags models use for leetcode languages, besides the language slug itself
+var langTags = map[string][]string{
+	"python":     {"py"},
+	"python3":    {"python", "py"},
+	"pythondata": {"python", "py", "python3", "pandas"},
+	"cpp":        {"c++", "cc", "cxx"},
+	"c":          {"h"},
+	"csharp":     {"c#", "cs"},
+	"golang":     {"go"},
+	"javascript": {"js", "node"},
+	"typescript": {"ts"},
+	"kotlin":     {"kt"},
+	"ruby":       {"rb"},
+	"rust":       {"rs"},
+	"bash":       {"sh", "shell", "zsh"},
+	"mysql":      {"sql"},
+	"mssql":      {"sql", "tsql", "t-sql"},
+	"oraclesql":  {"sql", "plsql", "oracle"},
+	"postgresql": {"sql", "postgres", "psql"},
+	"elixir":     {"ex", "exs"},
+	"erlang":     {"erl"},
+	"racket":     {"rkt", "scheme"},
+}
+
+var (
+	thinkRe       = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	fenceRe       = regexp.MustCompile("^[ \t]{0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	htmlCodeRe    = regexp.MustCompile(`(?is)<pre[^>]*>\s*(?:<code[^>]*>)?(.*?)(?:</code>\s*)?</pre>|<code[^>]*>(.*?)</code>`)
+	declarationRe = regexp.MustCompile(`\b(?:class|def|func|function|fn|fun|struct|interface|trait|impl|object|module|defmodule|sub|type)\s+([A-Za-z_]\w*)`)
+	callRe        = regexp.MustCompile(`([A-Za-z_]\w*)\s*\(`)
+)
+
+// words followed by a parenthesis which are not function names
+var nonSignatureWords = []string{"if", "for", "while", "switch", "return", "catch", "sizeof", "function", "func", "def", "fn", "new", "super", "self", "this", "init", "main", "print", "println", "printf", "public", "private", "static", "void"}
+
+type codeBlock struct {
+	tag        string
+	code       string
+	terminated bool
+}
+
+// ExtractCode extracts the solution code from the model answer. Fenced blocks are scored by the language tag
+// and by names from the snippet signature (class, method and function names), the best one wins.
+// Returns the code and the strategy used
+func ExtractCode(answer, lang, snippet string) (string, string) {
+	answer = thinkRe.ReplaceAllString(answer, "")
+	if strings.TrimSpace(answer) == "" {
+		return "", EXTRACT_NONE
+	}
+
+	blocks := fencedBlocks(answer)
+	if len(blocks) == 1 {
+		if !blocks[0].terminated {
+			return blocks[0].code, EXTRACT_UNTERMINATED
+		}
+		return blocks[0].code, EXTRACT_FENCED
+	}
+	if len(blocks) > 1 {
+		names := SignatureNames(snippet)
+		best := 0
+		bestScore := scoreBlock(blocks[0], lang, names)
+		for i := 1; i < len(blocks); i++ {
+			score := scoreBlock(blocks[i], lang, names)
+			// on equal scores the longer block wins: it is usually the full solution, not a usage example
+			if score > bestScore || (score == bestScore && len(blocks[i].code) > len(blocks[best].code)) {
+				best, bestScore = i, score
+			}
+		}
+		return blocks[best].code, EXTRACT_FENCED_SCORED
+	}
+
+	if m := htmlCodeRe.FindStringSubmatch(answer); m != nil {
+		code := m[1]
+		if code == "" {
+			code = m[2]
+		}
+		// inline <code> is a mention of an identifier rather than the solution
+		if strings.Contains(strings.TrimSpace(code), "\n") {
+			return html.UnescapeString(strings.Trim(code, "\n")) + "\n", EXTRACT_HTML
+		}
+	}
+
+	return answer, EXTRACT_RAW
+}
+
+// fencedBlocks finds ``` and ~~~ fenced blocks. A block opened, but not closed before the end of the answer
+// is returned as unterminated
+func fencedBlocks(answer string) []codeBlock {
+	blocks := []codeBlock{}
+	var current *codeBlock
+	var fence string
+	var lines []string
+	for _, line := range strings.Split(answer, "\n") {
+		m := fenceRe.FindStringSubmatch(line)
+		if current == nil {
+			if m != nil {
+				current = &codeBlock{tag: strings.ToLower(m[2])}
+				fence = m[1]
+				lines = nil
+			}
+			continue
+		}
+		// closing fence must be at least as long as the opening one and have no tag
+		if m != nil && m[2] == "" && m[1][0] == fence[0] && len(m[1]) >= len(fence) {
+			current.code = joinCodeLines(lines)
+			current.terminated = true
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if current != nil && len(lines) > 0 {
+		current.code = joinCodeLines(lines)
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+func joinCodeLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func scoreBlock(b codeBlock, lang string, names []string) int {
+	score := 0
+	if b.tag != "" {
+		if b.tag == lang || slices.Contains(langTags[lang], b.tag) {
+			score += 10
+		} else {
+			// block in another language, e.g. a shell command or an output sample
+			score -= 5
+		}
+	}
+	for _, name := range names {
+		quoted := regexp.QuoteMeta(name)
+		if regexp.MustCompile(`\b` + quoted + `\b`).MatchString(b.code) {
+			score += 3
+		}
+		// declared rather than called, e.g. "def name(" vs "obj.name("
+		if regexp.MustCompile(`(?m)(?:^|[\s*&>\]])` + quoted + `\s*[(:{<]`).MatchString(b.code) {
+			score += 2
+		}
+	}
+	if !b.terminated {
+		score -= 1
+	}
+	return score
+}
+
+// SignatureNames returns class, method and function names declared in the code snippet
+func SignatureNames(snippet string) []string {
+	names := []string{}
+	add := func(name string) {
+		if !slices.Contains(names, name) && !slices.Contains(nonSignatureWords, strings.ToLower(name)) {
+			names = append(names, name)
+		}
+	}
+	for _, line := range strings.Split(snippet, "\n") {
+		trimmed := strings.TrimSpace(line)
+		// commented out definitions, e.g. ListNode in linked list problems, are not a part of the solution
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		for _, m := range declarationRe.FindAllStringSubmatch(trimmed, -1) {
+			add(m[1])
+		}
+		for _, m := range callRe.FindAllStringSubmatch(trimmed, -1) {
+			add(m[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,153 @@
+package leetgptsolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pythonSnippet = `# Definition for singly-linked list.
+# class ListNode:
+#     def __init__(self, val=0, next=None):
+#         self.val = val
+#         self.next = next
+class Solution:
+    def addTwoNumbers(self, l1: Optional[ListNode], l2: Optional[ListNode]) -> Optional[ListNode]:
+        `
+
+func TestExtractCode(t *testing.T) {
+	tests := []struct {
+		name             string
+		answer           string
+		lang             string
+		snippet          string
+		expectedCode     string
+		expectedStrategy string
+	}{
+		{
+			name:             "single fenced block",
+			answer:           "Here is the solution:\n```python\nclass Solution:\n    pass\n```\nDone.",
+			lang:             "python3",
+			snippet:          pythonSnippet,
+			expectedCode:     "class Solution:\n    pass\n",
+			expectedStrategy: EXTRACT_FENCED,
+		},
+		{
+			name: "explanation block first",
+			answer: "The idea:\n```text\nwalk both lists, carry the sum\n```\n" +
+				"Code:\n```python\nclass Solution:\n    def addTwoNumbers(self, l1, l2):\n        return None\n```\n",
+			lang:             "python3",
+			snippet:          pythonSnippet,
+			expectedCode:     "class Solution:\n    def addTwoNumbers(self, l1, l2):\n        return None\n",
+			expectedStrategy: EXTRACT_FENCED_SCORED,
+		},
+		{
+			name: "untagged blocks scored by signature",
+			answer: "```\nclass Solution:\n    def addTwoNumbers(self, l1, l2):\n        return None\n```\n" +
+				"Usage:\n```\nprint(Solution().addTwoNumbers(a, b))\nprint(Solution().addTwoNumbers(c, d))\n```\n",
+			lang:             "python3",
+			snippet:          pythonSnippet,
+			expectedCode:     "class Solution:\n    def addTwoNumbers(self, l1, l2):\n        return None\n",
+			expectedStrategy: EXTRACT_FENCED_SCORED,
+		},
+		{
+			name:             "unterminated fence",
+			answer:           "```go\nfunc twoSum(nums []int, target int) []int {\n    return nil\n}",
+			lang:             "golang",
+			snippet:          "func twoSum(nums []int, target int) []int {\n    \n}",
+			expectedCode:     "func twoSum(nums []int, target int) []int {\n    return nil\n}\n",
+			expectedStrategy: EXTRACT_UNTERMINATED,
+		},
+		{
+			name:             "longer fence with inner fence",
+			answer:           "````markdown\n```\nnot a fence end\n```\n````",
+			lang:             "python3",
+			expectedCode:     "```\nnot a fence end\n```\n",
+			expectedStrategy: EXTRACT_FENCED,
+		},
+		{
+			name:             "html code",
+			answer:           "<p>Solution:</p><pre><code>SELECT a\nFROM t WHERE a &gt; 1;</code></pre>",
+			lang:             "mysql",
+			expectedCode:     "SELECT a\nFROM t WHERE a > 1;\n",
+			expectedStrategy: EXTRACT_HTML,
+		},
+		{
+			name:             "inline html code is not a solution",
+			answer:           "SELECT 1; -- see <code>t</code>",
+			lang:             "mysql",
+			expectedCode:     "SELECT 1; -- see <code>t</code>",
+			expectedStrategy: EXTRACT_RAW,
+		},
+		{
+			name:             "thinking is skipped",
+			answer:           "<think>\n```python\nwrong\n```\n</think>\n```python\nright\n```",
+			lang:             "python3",
+			expectedCode:     "right\n",
+			expectedStrategy: EXTRACT_FENCED,
+		},
+		{
+			name:             "raw answer",
+			answer:           "class Solution:\n    pass\n",
+			lang:             "python3",
+			expectedCode:     "class Solution:\n    pass\n",
+			expectedStrategy: EXTRACT_RAW,
+		},
+		{
+			name:             "empty answer",
+			answer:           " \n",
+			lang:             "python3",
+			expectedCode:     "",
+			expectedStrategy: EXTRACT_NONE,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, strategy := ExtractCode(test.answer, test.lang, test.snippet)
+			if code != test.expectedCode {
+				t.Errorf("expected code: %q, got: %q", test.expectedCode, code)
+			}
+			if strategy != test.expectedStrategy {
+				t.Errorf("expected strategy: %s, got: %s", test.expectedStrategy, strategy)
+			}
+		})
+	}
+}
+
+func TestSignatureNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippet  string
+		expected []string
+	}{
+		{
+			name:     "python with commented definitions",
+			snippet:  pythonSnippet,
+			expected: []string{"Solution", "addTwoNumbers"},
+		},
+		{
+			name:     "java",
+			snippet:  "class Solution {\n    public int[] twoSum(int[] nums, int target) {\n        \n    }\n}",
+			expected: []string{"Solution", "twoSum"},
+		},
+		{
+			name:     "go method",
+			snippet:  "type MinStack struct {\n}\n\nfunc Constructor() MinStack {\n}\n\nfunc (this *MinStack) Push(val int) {\n}",
+			expected: []string{"MinStack", "Constructor", "Push"},
+		},
+		{
+			name:     "sql",
+			snippet:  "# Write your MySQL query statement below\n",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := SignatureNames(test.snippet)
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected names: %#v, got: %#v", test.expected, names)
+			}
+		})
+	}
+}
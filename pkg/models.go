@@ -18,6 +18,10 @@ const (
 	MODEL_VENDOR_ANTHROPIC
 	MODEL_VENDOR_DEEPSEEK
 	MODEL_VENDOR_XAI
+	MODEL_VENDOR_MISTRAL
+	MODEL_VENDOR_QWEN
+	MODEL_VENDOR_MOONSHOT
+	MODEL_VENDOR_AZURE
 )
 
 var OpenAiModels = []string{
@@ -57,6 +61,27 @@ var XaiModels = []string{
 	"grok-code-fast-1-0825",
 }
 
+var MistralModels = []string{
+	"mistral-large-2411",
+	"mistral-medium-2505",
+	"codestral-2501",
+	"devstral-medium-2507",
+	"magistral-medium-2507",
+}
+
+var QwenModels = []string{
+	"qwen-max-2025-01-25",
+	"qwen-plus",
+	"qwen3-coder-plus",
+	"qwen3-235b-a22b",
+}
+
+var MoonshotModels = []string{
+	"kimi-k2-0711-preview",
+	"kimi-k2-0905-preview",
+	"moonshot-v1-128k",
+}
+
 var supportedModels []string
 
 func init() {
@@ -65,12 +90,17 @@ func init() {
 	supportedModels = append(supportedModels, AnthropicModels...)
 	supportedModels = append(supportedModels, DeepseekModels...)
 	supportedModels = append(supportedModels, XaiModels...)
+	supportedModels = append(supportedModels, MistralModels...)
+	supportedModels = append(supportedModels, QwenModels...)
+	supportedModels = append(supportedModels, MoonshotModels...)
 }
 
 func SupportedModels() []string {
 	return supportedModels
 }
 
+// GuessModelVendor never returns azure: it serves openai models under deployment names chosen by the owner,
+// so azure requires --model_vendor azure
 func GuessModelVendor(modelName string) int {
 	modelName = strings.ToLower(strings.TrimSpace(modelName))
 
@@ -91,6 +121,18 @@ func GuessModelVendor(modelName string) int {
 	case strings.HasPrefix(modelName, "grok"),
 		strings.HasPrefix(modelName, "xai"):
 		return MODEL_VENDOR_XAI
+	case strings.HasPrefix(modelName, "mistral"),
+		strings.HasPrefix(modelName, "codestral"),
+		strings.HasPrefix(modelName, "devstral"),
+		strings.HasPrefix(modelName, "magistral"),
+		strings.HasPrefix(modelName, "ministral"):
+		return MODEL_VENDOR_MISTRAL
+	case strings.HasPrefix(modelName, "qwen"),
+		strings.HasPrefix(modelName, "qwq"):
+		return MODEL_VENDOR_QWEN
+	case strings.HasPrefix(modelName, "kimi"),
+		strings.HasPrefix(modelName, "moonshot"):
+		return MODEL_VENDOR_MOONSHOT
 	default:
 		return MODEL_VENDOR_UNKNOWN
 	}
@@ -113,11 +155,39 @@ func ParseModelVendor(modelVendor string) (int, error) {
 		return MODEL_VENDOR_DEEPSEEK, nil
 	case "xai", "grok":
 		return MODEL_VENDOR_XAI, nil
+	case "mistral":
+		return MODEL_VENDOR_MISTRAL, nil
+	case "qwen", "dashscope", "alibaba":
+		return MODEL_VENDOR_QWEN, nil
+	case "moonshot", "kimi":
+		return MODEL_VENDOR_MOONSHOT, nil
+	case "azure", "azure_openai":
+		return MODEL_VENDOR_AZURE, nil
 	default:
 		return MODEL_VENDOR_UNKNOWN, fmt.Errorf("unknown model vendor: %s", modelVendor)
 	}
 }
 
+var modelVendorNames = map[int]string{
+	MODEL_VENDOR_OPENAI:    "openai",
+	MODEL_VENDOR_GOOGLE:    "google",
+	MODEL_VENDOR_ANTHROPIC: "anthropic",
+	MODEL_VENDOR_DEEPSEEK:  "deepseek",
+	MODEL_VENDOR_XAI:       "xai",
+	MODEL_VENDOR_MISTRAL:   "mistral",
+	MODEL_VENDOR_QWEN:      "qwen",
+	MODEL_VENDOR_MOONSHOT:  "moonshot",
+	MODEL_VENDOR_AZURE:     "azure",
+}
+
+// ModelVendorName returns the name of the vendor as accepted by ParseModelVendor
+func ModelVendorName(vendor int) string {
+	if name, ok := modelVendorNames[vendor]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 func ResolveModelVendor(modelName, modelVendor string) (int, error) {
 	vendorType, err := ParseModelVendor(modelVendor)
 	if err != nil {
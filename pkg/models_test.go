@@ -63,6 +63,9 @@ func TestGuessModelVendor(t *testing.T) {
 		{name: "anthropic claude", model: "claude-sonnet-4-5", expected: MODEL_VENDOR_ANTHROPIC},
 		{name: "deepseek", model: "deepseek-reasoner", expected: MODEL_VENDOR_DEEPSEEK},
 		{name: "xai grok", model: "grok-3-latest", expected: MODEL_VENDOR_XAI},
+		{name: "mistral codestral", model: "codestral-2501", expected: MODEL_VENDOR_MISTRAL},
+		{name: "qwen", model: "qwen3-coder-plus", expected: MODEL_VENDOR_QWEN},
+		{name: "moonshot kimi", model: "kimi-k2-0905-preview", expected: MODEL_VENDOR_MOONSHOT},
 		{name: "unknown", model: "my-custom-model", expected: MODEL_VENDOR_UNKNOWN},
 	}
 
@@ -86,6 +89,8 @@ func TestParseModelVendor(t *testing.T) {
 		{name: "openai", vendor: "openai", expected: MODEL_VENDOR_OPENAI, expectError: false},
 		{name: "vertex alias", vendor: "vertexai", expected: MODEL_VENDOR_GOOGLE, expectError: false},
 		{name: "anthropic alias", vendor: "claude", expected: MODEL_VENDOR_ANTHROPIC, expectError: false},
+		{name: "qwen alias", vendor: "dashscope", expected: MODEL_VENDOR_QWEN, expectError: false},
+		{name: "azure", vendor: "azure", expected: MODEL_VENDOR_AZURE, expectError: false},
 		{name: "unknown", vendor: "other", expected: MODEL_VENDOR_UNKNOWN, expectError: true},
 	}
 
@@ -102,6 +107,22 @@ func TestParseModelVendor(t *testing.T) {
 	}
 }
 
+func TestModelVendorName(t *testing.T) {
+	for vendor := MODEL_VENDOR_OPENAI; vendor <= MODEL_VENDOR_AZURE; vendor++ {
+		name := ModelVendorName(vendor)
+		parsed, err := ParseModelVendor(name)
+		if err != nil {
+			t.Fatalf("expected no error for vendor %d (%s), got %v", vendor, name, err)
+		}
+		if parsed != vendor {
+			t.Errorf("expected vendor %d for name %s, got %d", vendor, name, parsed)
+		}
+	}
+	if name := ModelVendorName(MODEL_VENDOR_UNKNOWN); name != "unknown" {
+		t.Errorf("expected unknown, got %s", name)
+	}
+}
+
 func TestResolveModelVendor(t *testing.T) {
 	modelVendor, err := ResolveModelVendor("my-custom-model", "")
 	if err == nil {
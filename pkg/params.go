@@ -0,0 +1,181 @@
+package leetgptsolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// param names as written after "@" in model names
+const (
+	PARAM_TEMPERATURE      = "temperature"
+	PARAM_TOP_P            = "top_p"
+	PARAM_MAX_TOKENS       = "max_tokens"
+	PARAM_REASONING_EFFORT = "reasoning_effort"
+	PARAM_THINKING_BUDGET  = "thinking_budget"
+	PARAM_THINKING         = "thinking"
+	PARAM_SEED             = "seed"
+)
+
+// ModelParams are typed model parameters. Unset params are nil, prompters use vendor defaults for them
+type ModelParams struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	MaxTokens       *int     `json:"max_tokens,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	// 0 disables thinking where the vendor allows it
+	ThinkingBudget *int `json:"thinking_budget,omitempty"`
+	// anthropic extended thinking in the api format, kept for model names used before thinking_budget
+	Thinking *ThinkingParams `json:"thinking,omitempty"`
+	Seed     *int            `json:"seed,omitempty"`
+}
+
+type ThinkingParams struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// params each vendor supports
+var vendorParams = map[int][]string{
+	MODEL_VENDOR_OPENAI:    {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_REASONING_EFFORT, PARAM_SEED},
+	MODEL_VENDOR_XAI:       {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_REASONING_EFFORT, PARAM_SEED},
+	MODEL_VENDOR_DEEPSEEK:  {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS},
+	MODEL_VENDOR_GOOGLE:    {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_THINKING_BUDGET, PARAM_SEED},
+	MODEL_VENDOR_ANTHROPIC: {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_THINKING_BUDGET, PARAM_THINKING},
+	MODEL_VENDOR_MISTRAL:   {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS},
+	MODEL_VENDOR_QWEN:      {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_SEED},
+	MODEL_VENDOR_MOONSHOT:  {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS},
+	MODEL_VENDOR_AZURE:     {PARAM_TEMPERATURE, PARAM_TOP_P, PARAM_MAX_TOKENS, PARAM_REASONING_EFFORT, PARAM_SEED},
+}
+
+// upper bounds of temperature where the vendor does not accept the usual 0..2
+var vendorMaxTemperature = map[int]float64{
+	MODEL_VENDOR_ANTHROPIC: 1,
+	MODEL_VENDOR_MISTRAL:   1.5,
+	MODEL_VENDOR_MOONSHOT:  1,
+}
+
+const DEFAULT_MAX_TEMPERATURE = 2
+
+// max_tokens of anthropic requests without the param, the api requires it
+const ANTHROPIC_DEFAULT_MAX_TOKENS = 4096
+
+// anthropic extended thinking limits: the least budget, and top_p is allowed only close to 1
+const (
+	ANTHROPIC_MIN_THINKING_BUDGET = 1024
+	ANTHROPIC_THINKING_MIN_TOP_P  = 0.95
+)
+
+// VendorSupportsParam tells if the api of the vendor accepts the param
+func VendorSupportsParam(vendor int, param string) bool {
+	return slices.Contains(vendorParams[vendor], param)
+}
+
+// ParseModelParams decodes params returned by ParseModelName and validates them for the vendor.
+// Unknown params and params the vendor does not support are errors, not ignored
+func ParseModelParams(params string, vendor int) (ModelParams, error) {
+	var p ModelParams
+	if params == "" {
+		return p, nil
+	}
+	supported, ok := vendorParams[vendor]
+	if !ok {
+		return p, fmt.Errorf("model params are not supported for vendor %d", vendor)
+	}
+
+	var keys map[string]json.RawMessage
+	err := json.Unmarshal([]byte(params), &keys)
+	if err != nil {
+		return p, fmt.Errorf("failed to parse model params: %w", err)
+	}
+	for key := range keys {
+		if !slices.Contains(supported, key) {
+			return p, fmt.Errorf("unknown model param %q, supported params: %s", key, strings.Join(supported, ", "))
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(params)))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&p)
+	if err != nil {
+		return p, fmt.Errorf("invalid model params: %w", err)
+	}
+	return p, p.validate(vendor)
+}
+
+func (p ModelParams) validate(vendor int) error {
+	maxTemperature, ok := vendorMaxTemperature[vendor]
+	if !ok {
+		maxTemperature = DEFAULT_MAX_TEMPERATURE
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > maxTemperature) {
+		return fmt.Errorf("%s must be between 0 and %v, got %v", PARAM_TEMPERATURE, maxTemperature, *p.Temperature)
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("%s must be between 0 and 1, got %v", PARAM_TOP_P, *p.TopP)
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", PARAM_MAX_TOKENS, *p.MaxTokens)
+	}
+	if p.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, p.ReasoningEffort) {
+		return fmt.Errorf("%s must be one of %s, got %q", PARAM_REASONING_EFFORT, strings.Join(ReasoningEfforts, ", "), p.ReasoningEffort)
+	}
+	if p.ThinkingBudget != nil && *p.ThinkingBudget < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", PARAM_THINKING_BUDGET, *p.ThinkingBudget)
+	}
+	if p.Thinking != nil {
+		if p.Thinking.Type != "enabled" && p.Thinking.Type != "disabled" {
+			return fmt.Errorf("%s.type must be enabled or disabled, got %q", PARAM_THINKING, p.Thinking.Type)
+		}
+		if p.Thinking.Type == "enabled" && p.Thinking.BudgetTokens <= 0 {
+			return fmt.Errorf("%s.budget_tokens must be positive when thinking is enabled", PARAM_THINKING)
+		}
+		if p.ThinkingBudget != nil {
+			return fmt.Errorf("%s and %s are mutually exclusive", PARAM_THINKING, PARAM_THINKING_BUDGET)
+		}
+	}
+	if vendor == MODEL_VENDOR_ANTHROPIC {
+		return p.validateAnthropicThinking()
+	}
+	return nil
+}
+
+// validateAnthropicThinking rejects params the api does not accept along with extended thinking
+func (p ModelParams) validateAnthropicThinking() error {
+	budget := p.AnthropicThinkingBudget()
+	if budget == 0 {
+		return nil
+	}
+	if budget < ANTHROPIC_MIN_THINKING_BUDGET {
+		return fmt.Errorf("thinking budget must be at least %d, got %d", ANTHROPIC_MIN_THINKING_BUDGET, budget)
+	}
+	maxTokens := ANTHROPIC_DEFAULT_MAX_TOKENS
+	if p.MaxTokens != nil {
+		maxTokens = *p.MaxTokens
+	}
+	if budget >= maxTokens {
+		return fmt.Errorf("thinking budget must be less than %s (%d), got %d", PARAM_MAX_TOKENS, maxTokens, budget)
+	}
+	if p.Temperature != nil {
+		return fmt.Errorf("%s can't be set with extended thinking", PARAM_TEMPERATURE)
+	}
+	if p.TopP != nil && *p.TopP < ANTHROPIC_THINKING_MIN_TOP_P {
+		return fmt.Errorf("%s must be between %v and 1 with extended thinking, got %v", PARAM_TOP_P, ANTHROPIC_THINKING_MIN_TOP_P, *p.TopP)
+	}
+	return nil
+}
+
+// AnthropicThinkingBudget returns the budget of extended thinking, 0 if thinking is disabled
+func (p ModelParams) AnthropicThinkingBudget() int {
+	if p.Thinking != nil && p.Thinking.Type == "enabled" {
+		return p.Thinking.BudgetTokens
+	}
+	if p.ThinkingBudget != nil {
+		return *p.ThinkingBudget
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package leetgptsolver
+
+import (
+	"testing"
+)
+
+func TestParseModelParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      string
+		vendor      int
+		expectError bool
+	}{
+		{name: "no params", params: "", vendor: MODEL_VENDOR_DEEPSEEK},
+		{name: "openai sampling", params: `{"seed":7,"temperature":0,"top_p":0.5}`, vendor: MODEL_VENDOR_OPENAI},
+		{name: "xai reasoning effort", params: `{"reasoning_effort":"high"}`, vendor: MODEL_VENDOR_XAI},
+		{name: "gemini thinking budget", params: `{"max_tokens":8192,"thinking_budget":0}`, vendor: MODEL_VENDOR_GOOGLE},
+		{name: "anthropic thinking object", params: `{"max_tokens":20000,"thinking":{"budget_tokens":16000,"type":"enabled"}}`, vendor: MODEL_VENDOR_ANTHROPIC},
+		{name: "unknown key", params: `{"temprature":0}`, vendor: MODEL_VENDOR_OPENAI, expectError: true},
+		{name: "key unsupported by the vendor", params: `{"seed":1}`, vendor: MODEL_VENDOR_DEEPSEEK, expectError: true},
+		{name: "unknown nested key", params: `{"thinking":{"budget":1024,"type":"enabled"}}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "wrong type", params: `{"max_tokens":"many"}`, vendor: MODEL_VENDOR_OPENAI, expectError: true},
+		{name: "temperature out of range", params: `{"temperature":3}`, vendor: MODEL_VENDOR_GOOGLE, expectError: true},
+		{name: "openai temperature above 1", params: `{"temperature":1.5}`, vendor: MODEL_VENDOR_OPENAI},
+		{name: "anthropic temperature above 1", params: `{"temperature":1.5}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic thinking with top_p close to 1", params: `{"thinking_budget":2048,"top_p":0.95}`, vendor: MODEL_VENDOR_ANTHROPIC},
+		{name: "anthropic thinking with temperature", params: `{"temperature":0.5,"thinking_budget":2048}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic thinking with low top_p", params: `{"thinking_budget":2048,"top_p":0.5}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic thinking budget too small", params: `{"thinking_budget":512}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic thinking budget above max_tokens", params: `{"max_tokens":8000,"thinking_budget":8000}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic thinking budget above default max_tokens", params: `{"thinking":{"budget_tokens":16000,"type":"enabled"}}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "anthropic disabled thinking with temperature", params: `{"temperature":0.5,"thinking_budget":0}`, vendor: MODEL_VENDOR_ANTHROPIC},
+		{name: "invalid reasoning effort", params: `{"reasoning_effort":"max"}`, vendor: MODEL_VENDOR_OPENAI, expectError: true},
+		{name: "thinking without budget", params: `{"thinking":{"type":"enabled"}}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+		{name: "both thinking params", params: `{"thinking":{"budget_tokens":2048,"type":"enabled"},"thinking_budget":1024}`, vendor: MODEL_VENDOR_ANTHROPIC, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseModelParams(test.params, test.vendor)
+			if (err != nil) != test.expectError {
+				t.Errorf("expected error: %v, got: %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAnthropicThinkingBudget(t *testing.T) {
+	p, err := ParseModelParams(`{"max_tokens":20000,"thinking":{"budget_tokens":16000,"type":"enabled"}}`, MODEL_VENDOR_ANTHROPIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget := p.AnthropicThinkingBudget(); budget != 16000 {
+		t.Errorf("expected budget: 16000, got: %d", budget)
+	}
+	p, err = ParseModelParams(`{"thinking_budget":1024}`, MODEL_VENDOR_ANTHROPIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget := p.AnthropicThinkingBudget(); budget != 1024 {
+		t.Errorf("expected budget: 1024, got: %d", budget)
+	}
+}
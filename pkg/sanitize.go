@@ -0,0 +1,407 @@
+package leetgptsolver
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// sanitizers fixing code before submission
+const (
+	// CRLF line endings, trailing whitespace, tabs in python, indentation of the whole code
+	SANITIZE_INDENTATION = "indentation"
+	// definitions which leetcode provides, like ListNode and TreeNode, and rust's "struct Solution;"
+	SANITIZE_DUPLICATE_STUBS = "duplicate_stubs"
+	// package clause in go, java, kotlin and scala
+	SANITIZE_PACKAGE_CLAUSE = "package_clause"
+	// imports of modules not available on leetcode
+	SANITIZE_DISALLOWED_IMPORTS = "disallowed_imports"
+	// main() functions and `if __name__ == "__main__":` test harnesses
+	SANITIZE_MAIN_FUNCTION = "main_function"
+)
+
+// DefaultSanitizers is the chain applied when no chain is configured for the language
+var DefaultSanitizers = []string{
+	SANITIZE_INDENTATION,
+	SANITIZE_DUPLICATE_STUBS,
+	SANITIZE_PACKAGE_CLAUSE,
+	SANITIZE_DISALLOWED_IMPORTS,
+	SANITIZE_MAIN_FUNCTION,
+}
+
+// DisallowedImports are modules not available on leetcode, by language
+var DisallowedImports = map[string][]string{
+	"python":  {"numpy", "scipy", "pytest", "unittest"},
+	"python3": {"numpy", "scipy", "pytest", "unittest"},
+}
+
+type sanitizer func(code, lang, snippet string) string
+
+var sanitizers = map[string]sanitizer{
+	SANITIZE_INDENTATION:        sanitizeIndentation,
+	SANITIZE_DUPLICATE_STUBS:    sanitizeDuplicateStubs,
+	SANITIZE_PACKAGE_CLAUSE:     sanitizePackageClause,
+	SANITIZE_DISALLOWED_IMPORTS: sanitizeDisallowedImports,
+	SANITIZE_MAIN_FUNCTION:      sanitizeMainFunction,
+}
+
+var pythonLangs = []string{"python", "python3", "pythondata"}
+
+// languages where blocks are delimited by braces
+var braceLangs = []string{"c", "cpp", "csharp", "java", "javascript", "typescript", "golang", "go", "rust", "kotlin", "scala", "swift", "php", "dart"}
+
+// ValidateSanitizers checks that all sanitizers in the chain exist
+func ValidateSanitizers(chain []string) error {
+	for _, name := range chain {
+		if _, ok := sanitizers[name]; !ok {
+			return fmt.Errorf("unknown sanitizer: %s", name)
+		}
+	}
+	return nil
+}
+
+// SanitizeCode applies the sanitizer chain to the code. Returns the code and names of sanitizers which changed it
+func SanitizeCode(code, lang, snippet string, chain []string) (string, []string, error) {
+	err := ValidateSanitizers(chain)
+	if err != nil {
+		return "", nil, err
+	}
+	applied := []string{}
+	for _, name := range chain {
+		sanitized := sanitizers[name](code, lang, snippet)
+		if sanitized != code {
+			applied = append(applied, name)
+			code = sanitized
+		}
+	}
+	return code, applied, nil
+}
+
+func sanitizeIndentation(code, lang, snippet string) string {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if slices.Contains(pythonLangs, lang) {
+			// mixing tabs and spaces is an error in python 3
+			trimmed := strings.TrimLeft(line, " \t")
+			indent := strings.ReplaceAll(line[:len(line)-len(trimmed)], "\t", "    ")
+			line = indent + trimmed
+		}
+		lines[i] = line
+	}
+
+	// code copied from an indented block keeps the indentation
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix != "" {
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, prefix)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var definitionRe = regexp.MustCompile(`\b(?:class|struct|type)\s+([A-Za-z_]\w*)`)
+
+// providedDefinitions returns names of types leetcode defines for the solution.
+// Snippets show them in comments, e.g. "Definition for singly-linked list."
+func providedDefinitions(snippet string) []string {
+	names := []string{}
+	for _, line := range strings.Split(snippet, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") && !strings.HasPrefix(trimmed, "*") && !strings.HasPrefix(trimmed, "/*") {
+			continue
+		}
+		for _, m := range definitionRe.FindAllStringSubmatch(trimmed, -1) {
+			if m[1] != "Solution" && !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+var rustSolutionStructRe = regexp.MustCompile(`(?m)^[ \t]*(?:pub\s+)?struct\s+Solution\s*;[ \t]*\n?`)
+
+func sanitizeDuplicateStubs(code, lang, snippet string) string {
+	if lang == "rust" {
+		// some models generate "pub struct Solution;" which is excessive and causes compilation error
+		code = rustSolutionStructRe.ReplaceAllString(code, "")
+	}
+
+	for _, name := range providedDefinitions(snippet) {
+		quoted := regexp.QuoteMeta(name)
+		switch {
+		case slices.Contains(pythonLangs, lang):
+			code = removePythonBlocks(code, regexp.MustCompile(`^class\s+`+quoted+`\b.*:$`))
+		case slices.Contains(braceLangs, lang):
+			// attributes like #[derive(...)] go away with the definition. Parentheses are not allowed
+			// before the brace, so functions with parameters of the type are kept
+			re := regexp.MustCompile(`(?m)^(?:[ \t]*#\[[^\]\n]*\][ \t]*\n)*[ \t]*(?:(?:public|private|protected|static|export|pub)\s+)*(?:class|struct|type|impl)\s+` + quoted + `\b[^{;()]*\{`)
+			code = removeBraceBlocks(code, re)
+		}
+	}
+	return code
+}
+
+var goPackageRe = regexp.MustCompile(`(?m)^package\s+\w+[ \t]*\n?`)
+var jvmPackageRe = regexp.MustCompile(`(?m)^package\s+[\w.]+[ \t]*;?[ \t]*\n?`)
+
+func sanitizePackageClause(code, lang, snippet string) string {
+	switch lang {
+	case "golang", "go":
+		return goPackageRe.ReplaceAllString(code, "")
+	case "java", "kotlin", "scala":
+		return jvmPackageRe.ReplaceAllString(code, "")
+	}
+	return code
+}
+
+func sanitizeDisallowedImports(code, lang, snippet string) string {
+	modules := DisallowedImports[lang]
+	if len(modules) == 0 {
+		return code
+	}
+	quoted := []string{}
+	for _, m := range modules {
+		quoted = append(quoted, regexp.QuoteMeta(m))
+	}
+	alternatives := strings.Join(quoted, "|")
+	re := regexp.MustCompile(`(?m)^[ \t]*(?:import|from)\s+(?:` + alternatives + `)(?:[\s.,].*)?(?:\n|$)`)
+	return re.ReplaceAllString(code, "")
+}
+
+var pythonMainRe = regexp.MustCompile(`^if\s+__name__\s*==\s*['"]__main__['"]\s*:`)
+var mainFunctionRe = regexp.MustCompile(`(?m)^[ \t]*(?:(?:public|private|static|async)\s+)*(?:int|void|func|fn|fun|def)\s+[Mm]ain\s*\([^)]*\)[^{;]*\{`)
+
+func sanitizeMainFunction(code, lang, snippet string) string {
+	switch {
+	case slices.Contains(pythonLangs, lang):
+		return removePythonBlocks(code, pythonMainRe)
+	case lang == "golang" || lang == "go":
+		return removeGoMain(code)
+	case slices.Contains(braceLangs, lang):
+		return removeBraceBlocks(code, mainFunctionRe)
+	}
+	return code
+}
+
+// removeGoMain removes main along with imports only main used, unused imports are compilation errors in go.
+// The code is left as is if it can't be parsed
+func removeGoMain(code string) string {
+	sanitized := removeBraceBlocks(code, mainFunctionRe)
+	if sanitized == code {
+		return code
+	}
+	imports, err := goImports(code)
+	if err != nil {
+		return code
+	}
+	remaining, err := goImports(sanitized)
+	if err != nil {
+		return code
+	}
+	// remove from the end, so offsets of preceding imports stay valid
+	for i := len(remaining) - 1; i >= 0; i-- {
+		imp := remaining[i]
+		if imp.used || !slices.ContainsFunc(imports, func(o goImport) bool { return o.path == imp.path && o.used }) {
+			continue
+		}
+		sanitized = sanitized[:imp.start] + sanitized[imp.end:]
+	}
+	return sanitized
+}
+
+type goImport struct {
+	path string
+	used bool
+	// the lines of the import spec, or of the whole declaration if it has only this spec
+	start, end int
+}
+
+// goImports returns imports of go code with or without the package clause
+func goImports(code string) ([]goImport, error) {
+	prefix := ""
+	if !goPackageRe.MatchString(code) {
+		prefix = "package solution\n"
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", prefix+code, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset - len(prefix)
+	}
+	imports := []goImport{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec)
+			importPath := strings.Trim(spec.Path.Value, "`\"")
+			name := path.Base(importPath)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			var node ast.Node = spec
+			if len(gen.Specs) == 1 {
+				node = gen
+			}
+			start, end := lineBounds(code, offset(node.Pos()), offset(node.End()))
+			imports = append(imports, goImport{
+				path: importPath,
+				// blank and dot imports can't be checked
+				used:  used[name] || name == "_" || name == ".",
+				start: start,
+				end:   end,
+			})
+		}
+	}
+	return imports, nil
+}
+
+// lineBounds extends the range to whole lines including the final newline
+func lineBounds(code string, start, end int) (int, int) {
+	start = strings.LastIndex(code[:start], "\n") + 1
+	if idx := strings.Index(code[end:], "\n"); idx != -1 {
+		end += idx + 1
+	} else {
+		end = len(code)
+	}
+	return start, end
+}
+
+// removePythonBlocks removes top level blocks which start with a line matching re
+func removePythonBlocks(code string, re *regexp.Regexp) string {
+	lines := strings.Split(code, "\n")
+	result := []string{}
+	inBlock := false
+	for _, line := range lines {
+		if inBlock {
+			if line == "" || line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+			inBlock = false
+		}
+		if re.MatchString(strings.TrimRight(line, " \t")) {
+			inBlock = true
+			continue
+		}
+		result = append(result, line)
+	}
+	// blank lines of the removed block include the one before the final newline
+	if strings.HasSuffix(code, "\n") && len(result) > 0 && result[len(result)-1] != "" {
+		result = append(result, "")
+	}
+	return strings.Join(result, "\n")
+}
+
+// removeBraceBlocks removes blocks which start with re (the match must end with the opening brace)
+// up to the matching closing brace, along with a semicolon after it
+func removeBraceBlocks(code string, re *regexp.Regexp) string {
+	for {
+		loc := re.FindStringIndex(code)
+		if loc == nil {
+			return code
+		}
+		end := matchingBrace(code, loc[1]-1)
+		if end == -1 {
+			// unbalanced braces, leave the code as is
+			return code
+		}
+		end += 1
+		if end < len(code) && code[end] == ';' {
+			end += 1
+		}
+		if end < len(code) && code[end] == '\n' {
+			end += 1
+		}
+		code = code[:loc[0]] + code[end:]
+	}
+}
+
+// matchingBrace returns the index of the brace closing the one at open, skipping strings and comments
+func matchingBrace(code string, open int) int {
+	depth := 0
+	for i := open; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"', '\'', '`':
+			if end := literalEnd(code, i); end != -1 {
+				i = end
+			}
+		case '/':
+			if i+1 < len(code) && code[i+1] == '/' {
+				for i < len(code) && code[i] != '\n' {
+					i++
+				}
+			} else if i+1 < len(code) && code[i+1] == '*' {
+				idx := strings.Index(code[i+2:], "*/")
+				if idx == -1 {
+					return -1
+				}
+				i += idx + 3
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// literalEnd returns the index of the quote closing the literal at start. Only backtick literals span lines,
+// so a quote without a closing one on the same line (like a rust lifetime) is not a literal
+func literalEnd(code string, start int) int {
+	quote := code[start]
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		case '\n':
+			if quote != '`' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+package leetgptsolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+const javaTreeSnippet = `/**
+ * Definition for a binary tree node.
+ * public class TreeNode {
+ *     int val;
+ *     TreeNode left;
+ *     TreeNode right;
+ * }
+ */
+class Solution {
+    public int maxDepth(TreeNode root) {
+
+    }
+}`
+
+func TestSanitizeCode(t *testing.T) {
+	tests := []struct {
+		name            string
+		code            string
+		lang            string
+		snippet         string
+		chain           []string
+		expectedCode    string
+		expectedApplied []string
+		expectError     bool
+	}{
+		{
+			name:            "clean code is not changed",
+			code:            "class Solution:\n    def f(self) -> int:\n        return 1\n",
+			lang:            "python3",
+			chain:           DefaultSanitizers,
+			expectedCode:    "class Solution:\n    def f(self) -> int:\n        return 1\n",
+			expectedApplied: []string{},
+		},
+		{
+			name:            "rust solution struct",
+			code:            "pub struct Solution;\n\nimpl Solution {\n    pub fn f() -> i32 { 1 }\n}\n",
+			lang:            "rust",
+			chain:           DefaultSanitizers,
+			expectedCode:    "\nimpl Solution {\n    pub fn f() -> i32 { 1 }\n}\n",
+			expectedApplied: []string{SANITIZE_DUPLICATE_STUBS},
+		},
+		{
+			name: "java provided class and main",
+			code: "package com.example;\n" +
+				"public class TreeNode {\n    int val;\n    TreeNode left, right;\n}\n" +
+				"class Solution {\n    public int maxDepth(TreeNode root) {\n        return root == null ? 0 : 1;\n    }\n" +
+				"    public static void main(String[] args) {\n        System.out.println(\"{\");\n    }\n}\n",
+			lang:            "java",
+			snippet:         javaTreeSnippet,
+			chain:           DefaultSanitizers,
+			expectedCode:    "class Solution {\n    public int maxDepth(TreeNode root) {\n        return root == null ? 0 : 1;\n    }\n}\n",
+			expectedApplied: []string{SANITIZE_DUPLICATE_STUBS, SANITIZE_PACKAGE_CLAUSE, SANITIZE_MAIN_FUNCTION},
+		},
+		{
+			name:            "go package and main",
+			code:            "package main\n\nimport \"fmt\"\n\nfunc f() int {\n\treturn 1\n}\n\nfunc main() {\n\tfmt.Println(f())\n}\n",
+			lang:            "golang",
+			chain:           []string{SANITIZE_PACKAGE_CLAUSE, SANITIZE_MAIN_FUNCTION},
+			expectedCode:    "\n\nfunc f() int {\n\treturn 1\n}\n\n",
+			expectedApplied: []string{SANITIZE_PACKAGE_CLAUSE, SANITIZE_MAIN_FUNCTION},
+		},
+		{
+			name: "go imports still used after main are kept",
+			code: "import (\n\t\"fmt\"\n\t\"sort\"\n\tstr \"strings\"\n)\n\n" +
+				"func f(a []int) string {\n\tsort.Ints(a)\n\treturn fmt.Sprint(a)\n}\n\n" +
+				"func main() {\n\tfmt.Println(str.ToUpper(f([]int{2, 1})))\n}\n",
+			lang:            "golang",
+			chain:           []string{SANITIZE_MAIN_FUNCTION},
+			expectedCode:    "import (\n\t\"fmt\"\n\t\"sort\"\n)\n\nfunc f(a []int) string {\n\tsort.Ints(a)\n\treturn fmt.Sprint(a)\n}\n\n",
+			expectedApplied: []string{SANITIZE_MAIN_FUNCTION},
+		},
+		{
+			name:            "go main is kept if the code does not parse",
+			code:            "import \"fmt\"\n\nfunc f() int {\n\treturn 1\n\nfunc main() {\n\tfmt.Println(f())\n}\n",
+			lang:            "golang",
+			chain:           []string{SANITIZE_MAIN_FUNCTION},
+			expectedCode:    "import \"fmt\"\n\nfunc f() int {\n\treturn 1\n\nfunc main() {\n\tfmt.Println(f())\n}\n",
+			expectedApplied: []string{},
+		},
+		{
+			name: "python provided class, imports and test harness",
+			code: "import numpy as np\nfrom typing import List\n" +
+				"class ListNode:\n    def __init__(self, val=0):\n        self.val = val\n\n" +
+				"class Solution:\n    def f(self, head: ListNode) -> int:\n        return 1\n" +
+				"if __name__ == \"__main__\":\n    print(Solution().f(None))\n",
+			lang:            "python3",
+			snippet:         "# Definition for singly-linked list.\n# class ListNode:\n#     def __init__(self, val=0, next=None):\nclass Solution:\n",
+			chain:           DefaultSanitizers,
+			expectedCode:    "from typing import List\nclass Solution:\n    def f(self, head: ListNode) -> int:\n        return 1\n",
+			expectedApplied: []string{SANITIZE_DUPLICATE_STUBS, SANITIZE_DISALLOWED_IMPORTS, SANITIZE_MAIN_FUNCTION},
+		},
+		{
+			name:            "python indentation",
+			code:            "    class Solution:\r\n    \tdef f(self):  \r\n    \t    return 1\r\n",
+			lang:            "python3",
+			chain:           []string{SANITIZE_INDENTATION},
+			expectedCode:    "class Solution:\n    def f(self):\n        return 1\n",
+			expectedApplied: []string{SANITIZE_INDENTATION},
+		},
+		{
+			name:            "rust lifetimes do not break brace matching",
+			code:            "fn f<'a>(s: &'a str) -> &'a str { s }\nfn main() {\n    let c = '}';\n}\n",
+			lang:            "rust",
+			chain:           []string{SANITIZE_MAIN_FUNCTION},
+			expectedCode:    "fn f<'a>(s: &'a str) -> &'a str { s }\n",
+			expectedApplied: []string{SANITIZE_MAIN_FUNCTION},
+		},
+		{
+			name:        "unknown sanitizer",
+			code:        "x",
+			lang:        "python3",
+			chain:       []string{"no_such_sanitizer"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, applied, err := SanitizeCode(test.code, test.lang, test.snippet, test.chain)
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error: %v, got: %v", test.expectError, err)
+			}
+			if test.expectError {
+				return
+			}
+			if code != test.expectedCode {
+				t.Errorf("expected code: %q, got: %q", test.expectedCode, code)
+			}
+			if !reflect.DeepEqual(applied, test.expectedApplied) {
+				t.Errorf("expected applied: %#v, got: %#v", test.expectedApplied, applied)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package leetgptsolver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type schemaReplacement struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// dialect-specific type replacements for mysql DDL, applied in order
+var schemaTypeReplacements = map[string][]schemaReplacement{
+	"postgresql": {
+		{regexp.MustCompile(`(?i)\bdatetime\b`), "TIMESTAMP"},
+		{regexp.MustCompile(`(?i)\btinyint\b(\(\d+\))?`), "SMALLINT"},
+		{regexp.MustCompile(`(?i)\bdouble\b(\s+precision\b)?`), "DOUBLE PRECISION"},
+	},
+	"mssql": {
+		{regexp.MustCompile(`(?i)\bboolean\b|\bbool\b`), "BIT"},
+		{regexp.MustCompile(`(?i)\btimestamp\b`), "DATETIME"},
+		{regexp.MustCompile(`(?i)\bdouble\b(\s+precision\b)?`), "FLOAT"},
+	},
+	"oraclesql": {
+		{regexp.MustCompile(`(?i)\bvarchar\b`), "VARCHAR2"},
+		{regexp.MustCompile(`(?i)\bdatetime\b`), "TIMESTAMP"},
+		{regexp.MustCompile(`(?i)\btext\b`), "CLOB"},
+		{regexp.MustCompile(`(?i)\bboolean\b|\bbool\b`), "NUMBER(1)"},
+		{regexp.MustCompile(`(?i)\btinyint\b(\(\d+\))?`), "SMALLINT"},
+		{regexp.MustCompile(`(?i)\bdouble\b(\s+precision\b)?`), "FLOAT"},
+	},
+}
+
+var createTableRe = regexp.MustCompile(`(?i)^\s*create\s+table\s+`)
+var ifNotExistsRe = regexp.MustCompile(`(?i)\s+if\s+not\s+exists\b`)
+var enumColumnRe = regexp.MustCompile(`(?i)(\w+)\s+enum\s*\(([^)]*)\)`)
+
+// IsCreateTable tells DDL from the test data in leetcode mysql schemas
+func IsCreateTable(stmt string) bool {
+	return createTableRe.MatchString(stmt)
+}
+
+// ConvertMysqlDDL converts a mysql create table statement to the sql dialect, mysql and unknown dialects
+// are returned as is
+func ConvertMysqlDDL(stmt, dialect string) string {
+	replacements, ok := schemaTypeReplacements[dialect]
+	if !ok {
+		return stmt
+	}
+
+	if dialect != "postgresql" {
+		stmt = ifNotExistsRe.ReplaceAllString(stmt, "")
+	}
+
+	// there are no enums outside of mysql, use varchar with a check constraint instead
+	varcharType := "VARCHAR"
+	if dialect == "oraclesql" {
+		varcharType = "VARCHAR2"
+	}
+	stmt = enumColumnRe.ReplaceAllStringFunc(stmt, func(s string) string {
+		m := enumColumnRe.FindStringSubmatch(s)
+		size := 1
+		for _, value := range strings.Split(m[2], ",") {
+			size = max(size, len(strings.Trim(strings.TrimSpace(value), `'"`)))
+		}
+		return fmt.Sprintf("%s %s(%d) CHECK (%s IN (%s))", m[1], varcharType, size, m[1], m[2])
+	})
+
+	for _, r := range replacements {
+		stmt = r.re.ReplaceAllString(stmt, r.repl)
+	}
+
+	return stmt
+}
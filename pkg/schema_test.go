@@ -0,0 +1,83 @@
+package leetgptsolver
+
+import "testing"
+
+func TestConvertMysqlDDL(t *testing.T) {
+	tests := []struct {
+		name     string
+		stmt     string
+		dialect  string
+		expected string
+	}{
+		{
+			name:     "mysql is not changed",
+			stmt:     "Create table If Not Exists Users (id int, created datetime)",
+			dialect:  "mysql",
+			expected: "Create table If Not Exists Users (id int, created datetime)",
+		},
+		{
+			name:     "unknown dialect is not changed",
+			stmt:     "Create table Users (id int)",
+			dialect:  "sqlite",
+			expected: "Create table Users (id int)",
+		},
+		{
+			name:     "postgresql keeps if not exists",
+			stmt:     "Create table If Not Exists Logs (id int, ts datetime, flag tinyint(1), score double)",
+			dialect:  "postgresql",
+			expected: "Create table If Not Exists Logs (id int, ts TIMESTAMP, flag SMALLINT, score DOUBLE PRECISION)",
+		},
+		{
+			name:     "mssql",
+			stmt:     "Create table If Not Exists Logs (active bool, ts timestamp, score double precision)",
+			dialect:  "mssql",
+			expected: "Create table Logs (active BIT, ts DATETIME, score FLOAT)",
+		},
+		{
+			name:     "oraclesql",
+			stmt:     "Create table If Not Exists Users (name varchar(30), bio text, active boolean, ts datetime)",
+			dialect:  "oraclesql",
+			expected: "Create table Users (name VARCHAR2(30), bio CLOB, active NUMBER(1), ts TIMESTAMP)",
+		},
+		{
+			name:     "enum in mssql",
+			stmt:     "Create table Orders (id int, status ENUM('open', 'closed'))",
+			dialect:  "mssql",
+			expected: "Create table Orders (id int, status VARCHAR(6) CHECK (status IN ('open', 'closed')))",
+		},
+		{
+			name:     "enum in oraclesql",
+			stmt:     "Create table Orders (status enum('a','bb'))",
+			dialect:  "oraclesql",
+			expected: "Create table Orders (status VARCHAR2(2) CHECK (status IN ('a','bb')))",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ConvertMysqlDDL(test.stmt, test.dialect); got != test.expected {
+				t.Errorf("expected: %q, got: %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsCreateTable(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected bool
+	}{
+		{stmt: "Create table If Not Exists Users (id int)", expected: true},
+		{stmt: "  CREATE TABLE Users (id int)", expected: true},
+		{stmt: "insert into Users (id) values ('1')", expected: false},
+		{stmt: "Truncate table Users", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.stmt, func(t *testing.T) {
+			if got := IsCreateTable(test.stmt); got != test.expected {
+				t.Errorf("expected: %v, got: %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -23,8 +24,14 @@ type Problem struct {
 	SubmissionsV2 map[string]map[string]Submission `json:"SubmissionsV2,omitempty"`
 	// metadata
 	// data populated on download
+	// leetcode site the problem was downloaded from, empty means leetcode.com
+	Site            string `json:"Site,omitempty"`
 	DownloadedAt    time.Time
 	CreatedAtApprox time.Time
+	// snapshots of the question taken on every download
+	History []QuestionSnapshot `json:"History,omitempty"`
+	// question content in other natural languages, keyed by language code
+	Translations map[string]string `json:"Translations,omitempty"`
 
 	// always recalculated on read
 	Path     string `json:"-"`
@@ -62,8 +69,16 @@ type Question struct {
 			}
 			// only for premium accounts
 			CompanyTagStats string
+			// only for sites with translations (leetcode.cn)
+			TranslatedTitle   string `json:"translatedTitle,omitempty"`
+			TranslatedContent string `json:"translatedContent,omitempty"`
+			// table definitions for database problems and dataframes for pandas problems
+			MysqlSchemas []string
+			DataSchemas  []string
 		}
 	}
+	// images referenced in the content, populated on download
+	Images []QuestionImage `json:"Images,omitempty"`
 	// OBSOLETE: data populated on download, to be removed in future. Use Problem.DownloadedAt, Problem.CreatedAtApprox instead
 	DownloadedAt    time.Time
 	CreatedAtApprox time.Time
@@ -74,7 +89,9 @@ type Question struct {
 	TotalSubmissions int
 	TotalAccepted    int
 	// calculated from total submissions and accepted
-	AcceptanceRate      float64
+	AcceptanceRate float64
+	// lowercased CategoryTitle: algorithms, database, shell, concurrency, pandas, javascript
+	Category            string
 	ContentFeatures     string
 	CodeSnippetFeatures map[string]string
 	Url                 string
@@ -90,6 +107,36 @@ type Solution struct {
 	SolvedAt     time.Time
 	PromptTokens int
 	OutputTokens int
+	// measured on streamed answers only, time to first token includes reasoning
+	TimeToFirstToken time.Duration `json:"TimeToFirstToken,omitempty"`
+	TokensPerSecond  float64       `json:"TokensPerSecond,omitempty"`
+	// number of images attached to the prompt
+	PromptImages int `json:"PromptImages,omitempty"`
+	// hashes of the question content and snippet the prompt was built from
+	ContentHash string `json:"ContentHash,omitempty"`
+	SnippetHash string `json:"SnippetHash,omitempty"`
+	// set on download when the question content or snippet has changed after prompting
+	ContentChanged bool `json:"ContentChanged,omitempty"`
+	// natural language of the question content in the prompt, empty means the original
+	ContentLang string `json:"ContentLang,omitempty"`
+	// named template the prompt was rendered from, empty means the template from the config
+	TemplateName string `json:"TemplateName,omitempty"`
+	// hash of the template text, changes with every template edit
+	TemplateHash string `json:"TemplateHash,omitempty"`
+	// system message sent along with the prompt
+	SystemPrompt string `json:"SystemPrompt,omitempty"`
+	// slugs of solved problems shown to the model before the prompt
+	FewShot []string `json:"FewShot,omitempty"`
+	// how TypedCode was extracted from Answer, see leetgptsolver.EXTRACT_* constants
+	ExtractStrategy string `json:"ExtractStrategy,omitempty"`
+	// result of the local syntax or compile check, if checked
+	LocalCheck *LocalCheck `json:"LocalCheck,omitempty"`
+	// results of running examples from the problem statement locally, if tested
+	LocalTest *LocalTest `json:"LocalTest,omitempty"`
+	// vendor batch the answer was collected from, empty for synchronous prompts
+	BatchId string `json:"BatchId,omitempty"`
+	// the answer was taken from the response cache, latency and tokens are of the original request
+	FromCache bool `json:"FromCache,omitempty"`
 }
 
 // this we submit to leetcode
@@ -112,6 +159,12 @@ type Submission struct {
 	SubmissionId  uint64
 	CheckResponse CheckResponse
 	SubmittedAt   time.Time
+	// sanitizers which changed the code before submission
+	Sanitizers []string `json:"Sanitizers,omitempty"`
+	// variant of the submitted code, see CODE_VARIANT_* constants
+	CodeVariant string `json:"CodeVariant,omitempty"`
+	// variants rejected with 403 before this one was submitted, or all rejected variants
+	RejectedVariants []string `json:"RejectedVariants,omitempty"`
 }
 
 func (p Problem) MarshalJSON() ([]byte, error) {
@@ -128,6 +181,24 @@ func (p Problem) MarshalJSON() ([]byte, error) {
 	return jsonBytes.Bytes(), nil
 }
 
+var problemLocks sync.Map
+
+// updateProblem re-reads the problem under a lock of the file, applies the update and saves the problem,
+// so workers prompting several models for the same problem keep solutions of each other
+func updateProblem(file string, update func(*Problem)) error {
+	lock, _ := problemLocks.LoadOrStore(file, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	var problem Problem
+	err := problem.ReadProblem(file)
+	if err != nil {
+		return err
+	}
+	update(&problem)
+	return problem.SaveProblemInto(file)
+}
+
 // should we use path field to save to, not a separate argument?
 func (p Problem) SaveProblemInto(destPath string) error {
 	jsonBytes, err := p.MarshalJSON()
@@ -164,6 +235,7 @@ func (p *Problem) ReadProblem(srcPath string) error {
 		return fmt.Errorf("failed to scan acRate: %w", err)
 	}
 
+	p.Question.Category = strings.ToLower(p.Question.Data.Question.CategoryTitle)
 	p.Question.ContentFeatures = p.Question.parseContentFeatures()
 	p.Question.CodeSnippetFeatures = map[string]string{}
 	for _, lang := range p.Question.Data.Question.CodeSnippets {
@@ -216,7 +288,16 @@ func (p *Problem) ReadProblem(srcPath string) error {
 }
 
 func (p Problem) Url() string {
-	return "https://leetcode.com/problems/" + p.Question.Data.Question.TitleSlug + "/"
+	return p.site().ProblemUrl(p.Question.Data.Question.TitleSlug)
+}
+
+func (p Problem) site() *Site {
+	site, err := siteByName(p.Site)
+	if err != nil {
+		log.Err(err).Msgf("invalid site of problem %s, using %s", p.Filename, DEFAULT_SITE)
+		site, _ = siteByName(DEFAULT_SITE)
+	}
+	return site
 }
 
 func (p Problem) GetSolution(model, lang string) (Solution, bool) {
@@ -231,6 +312,16 @@ func (p Problem) GetSolution(model, lang string) (Solution, bool) {
 	return Solution{}, false
 }
 
+func (p *Problem) setSolution(model, lang string, sol Solution) {
+	if p.SolutionsV2 == nil {
+		p.SolutionsV2 = map[string]map[string]Solution{}
+	}
+	if _, ok := p.SolutionsV2[model]; !ok {
+		p.SolutionsV2[model] = map[string]Solution{}
+	}
+	p.SolutionsV2[model][lang] = sol
+}
+
 func (p Problem) GetSubmission(model, lang string) (Submission, bool) {
 	if modelSubmissions, ok := p.SubmissionsV2[model]; ok {
 		if subm, ok := modelSubmissions[lang]; ok {
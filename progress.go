@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// outcomes of jobs, in the order of summary columns
+const (
+	OUTCOME_SOLVED       = "solved"
+	OUTCOME_DOWNLOADED   = "downloaded"
+	OUTCOME_ACCEPTED     = "accepted"
+	OUTCOME_NOT_ACCEPTED = "not_accepted"
+	OUTCOME_SKIPPED      = "skipped"
+	OUTCOME_ERROR        = "error"
+	// jobs interrupted when another job failed fatally
+	OUTCOME_CANCELED = "canceled"
+)
+
+var progressOutcomes = []string{
+	OUTCOME_SOLVED,
+	OUTCOME_DOWNLOADED,
+	OUTCOME_ACCEPTED,
+	OUTCOME_NOT_ACCEPTED,
+	OUTCOME_SKIPPED,
+	OUTCOME_ERROR,
+	OUTCOME_CANCELED,
+}
+
+// jobs without a model, like downloads, are counted under this name
+const PROGRESS_NO_MODEL = "-"
+
+// RunSummary is written with --summary_json when a command ends
+type RunSummary struct {
+	Command    string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Seconds    float64
+	Total      int
+	Done       int
+	// counts of outcomes of all models
+	Counts map[string]int
+	Models []ModelSummary
+}
+
+type ModelSummary struct {
+	Model  string
+	Counts map[string]int
+}
+
+// progress counts outcomes of jobs of a command by model. In progress mode every finished job logs
+// the progress with the throughput and ETA, and the summary table is printed at the end
+type progress struct {
+	command string
+	total   int
+	start   time.Time
+
+	mu     sync.Mutex
+	done   int
+	models []string
+	counts map[string]map[string]int
+}
+
+func newProgress(command string, total int) *progress {
+	return &progress{
+		command: command,
+		total:   total,
+		start:   time.Now(),
+		counts:  map[string]map[string]int{},
+	}
+}
+
+// add records the outcome of a job, safe for concurrent use
+func (p *progress) add(model, outcome string) {
+	if model == "" {
+		model = PROGRESS_NO_MODEL
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.counts[model]; !ok {
+		p.models = append(p.models, model)
+		p.counts[model] = map[string]int{}
+	}
+	p.counts[model][outcome] += 1
+	p.done += 1
+	if options.Progress {
+		log.Info().Msg(p.line())
+	}
+}
+
+// line formats the progress, the lock must be held
+func (p *progress) line() string {
+	elapsed := time.Since(p.start)
+	throughput := float64(p.done) / elapsed.Seconds()
+	eta := "unknown"
+	if throughput > 0 && p.total >= p.done {
+		eta = time.Duration(float64(p.total-p.done) / throughput * float64(time.Second)).Round(time.Second).String()
+	}
+	percent := 100.0
+	if p.total > 0 {
+		percent = 100 * float64(p.done) / float64(p.total)
+	}
+	parts := []string{fmt.Sprintf("Progress: %d/%d (%0.1f%%), %0.2f per second, ETA %s", p.done, p.total, percent, throughput, eta)}
+	for _, model := range p.models {
+		counts := []string{}
+		for _, outcome := range progressOutcomes {
+			if n := p.counts[model][outcome]; n > 0 {
+				counts = append(counts, fmt.Sprintf("%s %d", outcome, n))
+			}
+		}
+		parts = append(parts, model+": "+strings.Join(counts, ", "))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (p *progress) summary() RunSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	s := RunSummary{
+		Command:    p.command,
+		StartedAt:  p.start,
+		FinishedAt: now,
+		Seconds:    now.Sub(p.start).Seconds(),
+		Total:      p.total,
+		Done:       p.done,
+		Counts:     map[string]int{},
+	}
+	models := slices.Clone(p.models)
+	slices.Sort(models)
+	for _, model := range models {
+		counts := map[string]int{}
+		for outcome, n := range p.counts[model] {
+			counts[outcome] = n
+			s.Counts[outcome] += n
+		}
+		s.Models = append(s.Models, ModelSummary{Model: model, Counts: counts})
+	}
+	return s
+}
+
+// finish prints the summary table in progress mode and writes the summary to --summary_json
+func (p *progress) finish() {
+	s := p.summary()
+	if options.Progress {
+		printSummary(s)
+	}
+	if options.SummaryJson == "" {
+		return
+	}
+	err := writeSummary(s, options.SummaryJson)
+	if err != nil {
+		log.Err(err).Msg("Failed to write the summary")
+	}
+}
+
+// printSummary prints a tab-separated table with outcomes which occurred in the run. The table goes to stderr,
+// like logs, so stdout keeps only the json summary with --summary_json -
+func printSummary(s RunSummary) {
+	outcomes := []string{}
+	for _, outcome := range progressOutcomes {
+		if s.Counts[outcome] > 0 {
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	fmt.Fprintln(os.Stderr, strings.Join(append([]string{"Model", "Done"}, outcomes...), SEPARATOR))
+	rows := append(slices.Clone(s.Models), ModelSummary{Model: "total", Counts: s.Counts})
+	for _, m := range rows {
+		done := 0
+		for _, n := range m.Counts {
+			done += n
+		}
+		row := []string{m.Model, fmt.Sprint(done)}
+		for _, outcome := range outcomes {
+			row = append(row, fmt.Sprint(m.Counts[outcome]))
+		}
+		fmt.Fprintln(os.Stderr, strings.Join(row, SEPARATOR))
+	}
+	log.Info().Msgf("Finished %d of %d in %0.1f second(s)", s.Done, s.Total, s.Seconds)
+}
+
+// writeSummary writes the summary as json into the file, "-" is stdout
+func writeSummary(s RunSummary, file string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the summary: %w", err)
+	}
+	data = append(data, '\n')
+	if file == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
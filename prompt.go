@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	leetgptsolver "whisk/leetgptsolver/pkg"
@@ -21,33 +23,52 @@ import (
 	"github.com/rs/zerolog/log"
 	openai "github.com/sashabaranov/go-openai"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/time/rate"
 	"google.golang.org/genai"
 )
 
-type prompterFunc func(Question, string, string, string) (*Solution, error)
+type prompterFunc func(*ChatPrompt, string, leetgptsolver.ModelParams) (*Solution, error)
+
+// seed of vendors which support it, part of response cache keys
+const PROMPT_SEED = 42
+
+// ChatPrompt is a prompt rendered for a question, ready to be sent to a model
+type ChatPrompt struct {
+	Lang   string
+	Text   string
+	Images []PromptImage
+	// optional system message
+	System string
+	// demonstrations sent before the question
+	Shots []ChatShot
+	// template the prompt was rendered from
+	TemplateName string
+	TemplateHash string
+}
 
-func prompt(args []string, lang, modelName, modelVendor string) {
-	files, err := filenamesFromArgs(args)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to get files")
-		return
-	}
+// promptModel is a model prompted in the run, resolved from its name
+type promptModel struct {
+	name       string
+	id         string
+	params     string
+	typed      leetgptsolver.ModelParams
+	vendor     int
+	key        string
+	prompter   prompterFunc
+	withImages bool
+}
 
-	if modelName == "" {
-		log.Error().Msg("Model is not set")
-		return
-	}
+func newPromptModel(modelName, modelVendor string) (*promptModel, error) {
 	modelId, modelParams, err := leetgptsolver.ParseModelName(modelName)
 	if err != nil {
-		log.Err(err).Msg("failed to parse model")
-		return
+		return nil, fmt.Errorf("failed to parse model: %w", err)
 	}
-
 	resolvedVendor, err := leetgptsolver.ResolveModelVendor(modelId, modelVendor)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to resolve vendor for model %s", modelId)
-		return
+		return nil, fmt.Errorf("failed to resolve vendor for model %s: %w", modelId, err)
+	}
+	params, err := leetgptsolver.ParseModelParams(modelParams, resolvedVendor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params of model %s: %w", modelId, err)
 	}
 
 	var prompter prompterFunc
@@ -62,105 +83,284 @@ func prompt(args []string, lang, modelName, modelVendor string) {
 		prompter = promptDeepseek
 	case leetgptsolver.MODEL_VENDOR_XAI:
 		prompter = promptXai
+	case leetgptsolver.MODEL_VENDOR_MISTRAL:
+		prompter = promptMistral
+	case leetgptsolver.MODEL_VENDOR_QWEN:
+		prompter = promptQwen
+	case leetgptsolver.MODEL_VENDOR_MOONSHOT:
+		prompter = promptMoonshot
+	case leetgptsolver.MODEL_VENDOR_AZURE:
+		prompter = promptAzure
 	default:
-		log.Error().Msgf("No prompter found for model %s", modelId)
+		return nil, fmt.Errorf("no prompter found for model %s", modelId)
+	}
+
+	return &promptModel{
+		name:       modelName,
+		id:         modelId,
+		params:     modelParams,
+		typed:      params,
+		vendor:     resolvedVendor,
+		key:        resultKey(modelName),
+		prompter:   prompter,
+		withImages: options.PromptImages && vendorSupportsImages(resolvedVendor),
+	}, nil
+}
+
+type promptJob struct {
+	file  string
+	model *promptModel
+}
+
+// prompt asks every model for solutions of the problems. Models of different vendors are prompted in
+// parallel, each vendor with its own limits, so the run takes as long as the slowest vendor
+func prompt(args []string, lang string, modelNames []string, modelVendor string) {
+	if options.Collect {
+		collectBatches(args)
+		return
+	}
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
 		return
 	}
 
-	log.Info().Msgf("Prompting %d solutions...", len(files))
-	var solvedCnt atomic.Int64
-	var skippedCnt atomic.Int64
-	var errorsCnt atomic.Int64
-
-	promptLimiter := rate.NewLimiter(rate.Limit(options.PromptRateLimit), options.PromptRateBurst)
-	log.Debug().Msgf("Prompt limiter configured: parallelism=%d rate=%0.6f req/s burst=%d", options.PromptParallelism, options.PromptRateLimit, options.PromptRateBurst)
+	if len(modelNames) == 0 {
+		log.Error().Msg("Model is not set")
+		return
+	}
+	models := []*promptModel{}
+	for _, modelName := range modelNames {
+		m, err := newPromptModel(modelName, modelVendor)
+		if err != nil {
+			log.Err(err).Msgf("Invalid model %s", modelName)
+			return
+		}
+		models = append(models, m)
+	}
 
-	g, ctx := errgroup.WithContext(context.Background())
-	g.SetLimit(options.PromptParallelism)
+	shots, err := loadFewShots(options.FewShot, lang)
+	if err != nil {
+		log.Err(err).Msg("failed to load few-shot problems")
+		return
+	}
+	if options.Batch {
+		for _, m := range models {
+			promptBatch(files, lang, m.name, m.id, m.typed, m.vendor, m.withImages, shots)
+		}
+		return
+	}
 
-	for i, file := range files {
-		g.Go(func() error {
-			log.Info().Msgf("[%d/%d] Prompting %s for problem %s ...", i+1, len(files), modelName, file)
+	var cache *responseCache
+	if !options.NoCache {
+		cache = newResponseCache(options.CacheDir)
+	}
 
-			var problem Problem
-			err := problem.ReadProblem(file)
-			if err != nil {
-				errorsCnt.Add(1)
-				log.Err(err).Msg("Failed to read the problem")
-				return nil
-			}
-			if solved, ok := problem.GetSolution(modelName, lang); ok && !options.Force {
-				skippedCnt.Add(1)
-				log.Info().Msgf("Already solved at %s", solved.SolvedAt.String())
-				return nil
-			}
-			if problem.Question.FindSnippet(lang) == "" {
-				errorsCnt.Add(1)
-				log.Error().Msgf("Skipping problem %s: code snippet for language %s not found", file, lang)
-				return nil
+	// problems go in the outer loop, so all models of a vendor progress together
+	vendors := []int{}
+	jobs := map[int][]promptJob{}
+	for _, file := range files {
+		for _, m := range models {
+			if _, ok := jobs[m.vendor]; !ok {
+				vendors = append(vendors, m.vendor)
 			}
+			jobs[m.vendor] = append(jobs[m.vendor], promptJob{file: file, model: m})
+		}
+	}
+	limiters, err := newVendorLimiters(vendors)
+	if err != nil {
+		log.Err(err).Msg("Failed to configure prompt limiters")
+		return
+	}
 
-			solution, err := promptWithRetries(ctx, promptLimiter, prompter, problem.Question, lang, modelId, modelParams)
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
+	total := len(files) * len(models)
+	log.Info().Msgf("Prompting %d solutions of %d model(s)...", total, len(models))
+	var startedCnt atomic.Int64
+	tracker := newProgress("prompt", total)
+	journal := openJournal("prompt")
+
+	var wg sync.WaitGroup
+	for _, vendor := range vendors {
+		limiter := limiters[vendor]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// a fatal error stops models of the vendor only
+			g, ctx := errgroup.WithContext(context.Background())
+			g.SetLimit(limiter.parallelism)
+
+			for _, job := range jobs[vendor] {
+				g.Go(func() error {
+					file, m := job.file, job.model
+					log.Info().Msgf("[%d/%d] Prompting %s for problem %s ...", startedCnt.Add(1), total, m.name, file)
+					done := func(outcome string, solution *Solution, err error) {
+						tracker.add(m.name, outcome)
+						event := JournalEvent{Event: JOURNAL_PROMPT, Problem: file, Model: m.name, Lang: lang, Status: outcome}
+						journal.record(errorEvent(solutionEvent(event, solution), err))
+					}
+
+					problem, chatPrompt, err := preparePrompt(file, m.key, lang, m.withImages, shots)
+					if errors.Is(err, errAlreadySolved) {
+						done(OUTCOME_SKIPPED, nil, nil)
+						log.Info().Msg(err.Error())
+						return nil
+					}
+					if err != nil {
+						done(OUTCOME_ERROR, nil, err)
+						if errors.Is(err, ErrFatal) {
+							log.Error().Err(err).Msg("Failed to make prompt. Aborting...")
+							return err
+						}
+						log.Err(err).Msgf("Skipping problem %s", file)
+						return nil
+					}
+
+					var solution *Solution
+					cacheKey := ""
+					if cache != nil {
+						cacheKey = cache.key(chatPrompt, m.vendor, m.id, m.params)
+						if cached, ok := cache.get(cacheKey); ok {
+							log.Info().Msgf("Answer found in the response cache, solved at %s", cached.SolvedAt.String())
+							solution = cached
+							solution.FromCache = true
+						}
+					}
+					if solution == nil {
+						solution, err = promptWithRetries(ctx, limiter, m.prompter, chatPrompt, m.id, m.typed)
+						if err != nil {
+							if errors.Is(err, context.Canceled) {
+								done(OUTCOME_CANCELED, nil, err)
+								return nil
+							}
+							done(OUTCOME_ERROR, nil, err)
+							if errors.Is(err, ErrFatal) {
+								log.Error().Err(err).Msg("Aborting...")
+								return err
+							}
+							log.Err(err).Msg("Failed to get a solution")
+							return nil
+						}
+						if cache != nil {
+							err = cache.put(cacheKey, solution)
+							if err != nil {
+								log.Err(err).Msg("Failed to cache the answer")
+							}
+						}
+					}
+
+					setPromptFields(solution, problem, chatPrompt)
+					// other models may have saved their solutions to the file since it was read
+					err = updateProblem(file, func(p *Problem) {
+						storeSolution(p, m.key, solution)
+					})
+					if err != nil {
+						done(OUTCOME_ERROR, solution, err)
+						log.Err(err).Msg("Failed to save the solution")
+						return nil
+					}
+					log.Info().Msgf("Got %d line(s) of code from %s in %0.1f second(s)", strings.Count(solution.TypedCode, "\n"), m.name, solution.Latency.Seconds())
+
+					done(OUTCOME_SOLVED, solution, nil)
 					return nil
-				}
-				errorsCnt.Add(1)
-				if errors.Is(err, ErrFatal) {
-					log.Error().Err(err).Msg("Aborting...")
-					return err
-				}
-				log.Err(err).Msg("Failed to get a solution")
-				return nil
-			}
-
-			log.Info().Msgf("Got %d line(s) of code in %0.1f second(s)", strings.Count(solution.TypedCode, "\n"), solution.Latency.Seconds())
-			if problem.SolutionsV2 == nil {
-				problem.SolutionsV2 = map[string]map[string]Solution{}
-			}
-			if _, ok := problem.SolutionsV2[modelName]; !ok {
-				problem.SolutionsV2[modelName] = map[string]Solution{}
-			}
-			problem.SolutionsV2[modelName][lang] = *solution
-			if problem.SubmissionsV2 == nil {
-				problem.SubmissionsV2 = map[string]map[string]Submission{}
-			}
-			if _, ok := problem.SubmissionsV2[modelName]; !ok {
-				problem.SubmissionsV2[modelName] = map[string]Submission{}
+				})
 			}
-			problem.SubmissionsV2[modelName][lang] = Submission{} // new solutions clears old submissions
-			err = problem.SaveProblemInto(file)
-			if err != nil {
-				errorsCnt.Add(1)
-				log.Err(err).Msg("Failed to save the solution")
-				return nil
+			if err := g.Wait(); err != nil {
+				log.Err(err).Msgf("Prompting %s models stopped due to fatal error", limiter.name)
 			}
+		}()
+	}
+	wg.Wait()
+	summary := tracker.summary()
+	log.Info().Msgf("Files processed: %d", len(files))
+	log.Info().Msgf("Skipped problems: %d", summary.Counts[OUTCOME_SKIPPED])
+	log.Info().Msgf("Problems solved successfully: %d", summary.Counts[OUTCOME_SOLVED])
+	if cache != nil {
+		log.Info().Msgf("Response cache: %d hits, %d misses", cache.hits.Load(), cache.misses.Load())
+	}
+	log.Info().Msgf("Errors: %d", summary.Counts[OUTCOME_ERROR])
+	tracker.finish()
+	journal.close(summary)
+}
 
-			solvedCnt.Add(1)
-			return nil
-		})
+var errAlreadySolved = errors.New("already solved")
+
+// preparePrompt reads the problem and renders the prompt for it. Problems solved before are skipped
+// with errAlreadySolved unless forced, failures to render the prompt are fatal
+func preparePrompt(file, key, lang string, withImages bool, shots []fewShot) (Problem, *ChatPrompt, error) {
+	var problem Problem
+	err := problem.ReadProblem(file)
+	if err != nil {
+		return problem, nil, fmt.Errorf("failed to read the problem: %w", err)
 	}
-	if err := g.Wait(); err != nil {
-		log.Err(err).Msg("Prompting stopped due to fatal error")
+	if solved, ok := problem.GetSolution(key, lang); ok && !options.Force {
+		return problem, nil, fmt.Errorf("%w at %s", errAlreadySolved, solved.SolvedAt.String())
+	}
+	if problem.Question.FindSnippet(lang) == "" {
+		return problem, nil, fmt.Errorf("code snippet for language %s not found", lang)
+	}
+	question, err := problem.QuestionInLang(options.ContentLang)
+	if err != nil {
+		return problem, nil, err
+	}
+
+	chatPrompt, err := generatePrompt(question, lang, withImages, shots)
+	if err != nil {
+		return problem, nil, NewFatalError(err)
+	}
+	if len(problem.Question.Images) > 0 && len(chatPrompt.Images) == 0 {
+		log.Warn().Msgf("Problem %s has images, but they are not attached to the prompt", file)
+	}
+	log.Debug().Msgf("Generated %d line(s) of code prompt with %d image(s) and %d few-shot example(s)", strings.Count(chatPrompt.Text, "\n"), len(chatPrompt.Images), len(chatPrompt.Shots))
+	if chatPrompt.System != "" {
+		log.Trace().Msgf("Generated system message:\n%s", chatPrompt.System)
+	}
+	log.Trace().Msgf("Generated prompt:\n%s", chatPrompt.Text)
+	return problem, chatPrompt, nil
+}
+
+// setPromptFields records how the prompt was made on the solution
+func setPromptFields(solution *Solution, problem Problem, p *ChatPrompt) {
+	solution.ContentHash = problem.Question.ContentHash()
+	solution.ContentLang = options.ContentLang
+	solution.SnippetHash = problem.Question.SnippetHash(p.Lang)
+	solution.TemplateName = p.TemplateName
+	solution.TemplateHash = p.TemplateHash
+	solution.SystemPrompt = p.System
+	for _, shot := range p.Shots {
+		solution.FewShot = append(solution.FewShot, shot.Slug)
 	}
-	log.Info().Msgf("Files processed: %d", len(files))
-	log.Info().Msgf("Skipped problems: %d", skippedCnt.Load())
-	log.Info().Msgf("Problems solved successfully: %d", solvedCnt.Load())
-	log.Info().Msgf("Errors: %d", errorsCnt.Load())
 }
 
-func promptWithRetries(ctx context.Context, limiter *rate.Limiter, prompter prompterFunc, q Question, lang, modelId, modelParams string) (*Solution, error) {
+// storeSolution extracts the code from the answer and stores the solution under the result key
+func storeSolution(problem *Problem, key string, solution *Solution) {
+	lang := solution.Lang
+	solution.TypedCode, solution.ExtractStrategy = leetgptsolver.ExtractCode(solution.Answer, lang, problem.Question.FindSnippet(lang))
+	if solution.ExtractStrategy != leetgptsolver.EXTRACT_FENCED {
+		log.Warn().Msgf("Code extracted with %s strategy", solution.ExtractStrategy)
+	}
+	problem.setSolution(key, lang, *solution)
+	if problem.SubmissionsV2 == nil {
+		problem.SubmissionsV2 = map[string]map[string]Submission{}
+	}
+	if _, ok := problem.SubmissionsV2[key]; !ok {
+		problem.SubmissionsV2[key] = map[string]Submission{}
+	}
+	problem.SubmissionsV2[key][lang] = Submission{} // new solutions clears old submissions
+}
+
+func promptWithRetries(ctx context.Context, limiter *vendorLimiter, prompter prompterFunc, p *ChatPrompt, modelId string, params leetgptsolver.ModelParams) (*Solution, error) {
 	maxRetries := options.Retries
 	var lastErr error
 	for i := 0; i < maxRetries; i++ {
 		log.Trace().Msgf("Attempt %d of %d...", i+1, maxRetries)
-		if err := limiter.Wait(ctx); err != nil {
+		if err := limiter.wait(ctx, p); err != nil {
 			return nil, err
 		}
 
-		solution, err := prompter(q, lang, modelId, modelParams)
+		solution, err := prompter(p, modelId, params)
 		if err == nil {
 			// success
+			limiter.charge(p, solution)
 			return solution, nil
 		}
 		lastErr = err
@@ -183,29 +383,20 @@ func promptWithRetries(ctx context.Context, limiter *rate.Limiter, prompter prom
 	return nil, fmt.Errorf("failed to get a solution after retries")
 }
 
-func promptOpenAi(q Question, lang, modelName, params string) (*Solution, error) {
+func promptOpenAi(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
 	client := openai.NewClient(options.ChatgptApiKey)
-	lang, prompt, err := generatePrompt(q, lang)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to make prompt: %w", err))
-	}
-	log.Debug().Msgf("Generated %d line(s) of code prompt", strings.Count(prompt, "\n"))
-	log.Trace().Msgf("Generated prompt:\n%s", prompt)
+	return chatOpenAi(client, openAiRequest(p, modelName, params), p)
+}
 
-	seed := int(42)
+// chatOpenAi sends the request to openai or an openai compatible api, streaming if enabled
+func chatOpenAi(client *openai.Client, request openai.ChatCompletionRequest, p *ChatPrompt) (*Solution, error) {
+	if options.Stream {
+		return streamOpenAi(client, request, p)
+	}
 	t0 := time.Now()
 	resp, err := client.CreateChatCompletion(
 		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: modelName,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			Seed: &seed,
-		},
+		request,
 	)
 	latency := time.Since(t0)
 	if err != nil {
@@ -217,10 +408,10 @@ func promptOpenAi(q Question, lang, modelName, params string) (*Solution, error)
 	answer := resp.Choices[0].Message.Content
 	log.Trace().Msgf("Got answer:\n%s", answer)
 	return &Solution{
-		Lang:         lang,
-		Prompt:       prompt,
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
 		Answer:       answer,
-		TypedCode:    extractCode(answer),
 		Model:        resp.Model,
 		SolvedAt:     time.Now(),
 		Latency:      latency,
@@ -229,29 +420,62 @@ func promptOpenAi(q Question, lang, modelName, params string) (*Solution, error)
 	}, nil
 }
 
-func promptDeepseek(q Question, lang, modelName, params string) (*Solution, error) {
-	client := deepseek.NewClient(options.DeepseekApiKey)
-	lang, prompt, err := generatePrompt(q, lang)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to make prompt: %w", err))
+// openAiRequest is used for openai and openai compatible apis
+func openAiRequest(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) openai.ChatCompletionRequest {
+	seed := PROMPT_SEED
+	if params.Seed != nil {
+		seed = *params.Seed
+	}
+	request := openai.ChatCompletionRequest{
+		Model:           modelName,
+		Messages:        openAiMessages(p),
+		Seed:            &seed,
+		ReasoningEffort: params.ReasoningEffort,
+	}
+	if params.Temperature != nil {
+		request.Temperature = openAiFloat(*params.Temperature)
 	}
-	log.Debug().Msgf("Generated %d line(s) of code prompt", strings.Count(prompt, "\n"))
-	log.Trace().Msgf("Generated prompt:\n%s", prompt)
+	if params.TopP != nil {
+		request.TopP = openAiFloat(*params.TopP)
+	}
+	if params.MaxTokens != nil {
+		request.MaxCompletionTokens = *params.MaxTokens
+	}
+	return request
+}
 
+// zero values are omitted from openai requests, so an explicit zero is sent as the smallest float
+func openAiFloat(v float64) float32 {
+	if v == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return float32(v)
+}
+
+func promptDeepseek(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
+	client := deepseek.NewClient(options.DeepseekApiKey)
 	t0 := time.Now()
 	client.Timeout = 15 * time.Minute
+	request := deepseek.ChatCompletionRequest{
+		Model:       modelName,
+		Messages:    deepseekMessages(p),
+		Temperature: 0.0,
+	}
+	if params.Temperature != nil {
+		request.Temperature = openAiFloat(*params.Temperature)
+	}
+	if params.TopP != nil {
+		request.TopP = openAiFloat(*params.TopP)
+	}
+	if params.MaxTokens != nil {
+		request.MaxTokens = *params.MaxTokens
+	}
+	if options.Stream {
+		return streamDeepseek(client, request, p)
+	}
 	resp, err := client.CreateChatCompletion(
 		context.Background(),
-		&deepseek.ChatCompletionRequest{
-			Model: modelName,
-			Messages: []deepseek.ChatCompletionMessage{
-				{
-					Role:    deepseek.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			Temperature: 0.0,
-		},
+		&request,
 	)
 	latency := time.Since(t0)
 	if err != nil {
@@ -263,10 +487,10 @@ func promptDeepseek(q Question, lang, modelName, params string) (*Solution, erro
 	answer := resp.Choices[0].Message.Content
 	log.Trace().Msgf("Got answer:\n%s", answer)
 	return &Solution{
-		Lang:         lang,
-		Prompt:       prompt,
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
 		Answer:       answer,
-		TypedCode:    extractCode(answer),
 		Model:        resp.Model,
 		SolvedAt:     time.Now(),
 		Latency:      latency,
@@ -276,115 +500,173 @@ func promptDeepseek(q Question, lang, modelName, params string) (*Solution, erro
 }
 
 // very dirty
-func promptXai(q Question, lang, modelName, params string) (*Solution, error) {
+func promptXai(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
 	config := openai.DefaultConfig(options.XaiApiKey)
 	config.BaseURL = "https://api.x.ai/v1"
 	client := openai.NewClientWithConfig(config)
+	return chatOpenAi(client, openAiRequest(p, modelName, params), p)
+}
 
-	lang, prompt, err := generatePrompt(q, lang)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to make prompt: %w", err))
-	}
-	log.Debug().Msgf("Generated %d line(s) of code prompt", strings.Count(prompt, "\n"))
-	log.Trace().Msgf("Generated prompt:\n%s", prompt)
+// default endpoints of vendors with openai compatible apis, overridden by *_base_url options
+const (
+	MISTRAL_BASE_URL  = "https://api.mistral.ai/v1"
+	QWEN_BASE_URL     = "https://dashscope-intl.aliyuncs.com/compatible-mode/v1"
+	MOONSHOT_BASE_URL = "https://api.moonshot.ai/v1"
+	// the default of the client is too old for recent models
+	AZURE_OPENAI_API_VERSION = "2024-10-21"
+)
 
-	var customParams struct {
-		ReasoningEffort string `json:"reasoning_effort"`
-	}
-	if params != "" {
-		err = json.Unmarshal([]byte(params), &customParams)
-		if err != nil {
-			return nil, NewFatalError(fmt.Errorf("failed to parse custom params: %w", err))
+func promptMistral(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
+	client := compatibleClient(options.MistralApiKey, options.MistralBaseUrl, MISTRAL_BASE_URL)
+	return chatOpenAi(client, compatibleRequest(p, modelName, params, leetgptsolver.MODEL_VENDOR_MISTRAL), p)
+}
+
+func promptQwen(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
+	client := compatibleClient(options.QwenApiKey, options.QwenBaseUrl, QWEN_BASE_URL)
+	return chatOpenAi(client, compatibleRequest(p, modelName, params, leetgptsolver.MODEL_VENDOR_QWEN), p)
+}
+
+func promptMoonshot(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
+	client := compatibleClient(options.MoonshotApiKey, options.MoonshotBaseUrl, MOONSHOT_BASE_URL)
+	return chatOpenAi(client, compatibleRequest(p, modelName, params, leetgptsolver.MODEL_VENDOR_MOONSHOT), p)
+}
+
+// promptAzure calls a deployment of an openai model. The model name is mapped to the deployment
+// with azure_openai_deployments, models without a mapping are expected to be deployed under their own name
+func promptAzure(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
+	if options.AzureOpenaiEndpoint == "" {
+		return nil, NewFatalError(errors.New("azure_openai_endpoint is not set"))
+	}
+	config := openai.DefaultAzureConfig(options.AzureOpenaiApiKey, options.AzureOpenaiEndpoint)
+	if options.AzureOpenaiApiVersion != "" {
+		config.APIVersion = options.AzureOpenaiApiVersion
+	} else {
+		config.APIVersion = AZURE_OPENAI_API_VERSION
+	}
+	config.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := options.AzureOpenaiDeployments[model]; ok {
+			return deployment
 		}
-		log.Debug().Msgf("using custom params: %+v", customParams)
+		return model
 	}
+	client := openai.NewClientWithConfig(config)
+	return chatOpenAi(client, openAiRequest(p, modelName, params), p)
+}
 
-	seed := int(42)
-	completionRequest := openai.ChatCompletionRequest{
-		Model: modelName,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Seed: &seed,
+func compatibleClient(apiKey, baseUrl, defaultBaseUrl string) *openai.Client {
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = defaultBaseUrl
+	if baseUrl != "" {
+		config.BaseURL = baseUrl
 	}
-	if customParams.ReasoningEffort != "" {
-		completionRequest.ReasoningEffort = customParams.ReasoningEffort
+	return openai.NewClientWithConfig(config)
+}
+
+// compatibleRequest adapts the openai request to apis which know max_tokens only and may not accept a seed
+func compatibleRequest(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams, vendor int) openai.ChatCompletionRequest {
+	request := openAiRequest(p, modelName, params)
+	request.MaxTokens, request.MaxCompletionTokens = request.MaxCompletionTokens, 0
+	if !leetgptsolver.VendorSupportsParam(vendor, leetgptsolver.PARAM_SEED) {
+		request.Seed = nil
 	}
+	return request
+}
 
-	t0 := time.Now()
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		completionRequest,
-	)
-	latency := time.Since(t0)
-	if err != nil {
-		return nil, err
+// gemini models are served either by vertex ai of a google cloud project or by the gemini api with an api key
+const (
+	GEMINI_BACKEND_VERTEXAI = "vertexai"
+	GEMINI_BACKEND_GEMINI   = "gemini"
+)
+
+var googleClientOnce sync.Once
+var googleClient *genai.Client
+var googleClientErr error
+
+// sharedGoogleClient creates the client on first use, the client is safe for concurrent use by prompt workers
+func sharedGoogleClient() (*genai.Client, error) {
+	googleClientOnce.Do(func() {
+		googleClient, googleClientErr = newGoogleClient()
+	})
+	return googleClient, googleClientErr
+}
+
+func newGoogleClient() (*genai.Client, error) {
+	config := &genai.ClientConfig{}
+	switch options.GeminiBackend {
+	case GEMINI_BACKEND_GEMINI:
+		if options.GeminiApiKey == "" {
+			return nil, errors.New("gemini_api_key is required for the gemini backend")
+		}
+		config.Backend = genai.BackendGeminiAPI
+		config.APIKey = options.GeminiApiKey
+	case GEMINI_BACKEND_VERTEXAI, "":
+		credJson, err := os.ReadFile(options.GeminiCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file: %w", err)
+		}
+		creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+			CredentialsJSON: credJson,
+			Scopes:          []string{"https://www.googleapis.com/auth/cloud-platform"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials: %w", err)
+		}
+		config.Backend = genai.BackendVertexAI
+		config.Project = options.GeminiProjectId
+		config.Location = options.GeminiRegion
+		config.Credentials = creds
+	default:
+		return nil, fmt.Errorf("unknown gemini backend %q, expected %s or %s", options.GeminiBackend, GEMINI_BACKEND_VERTEXAI, GEMINI_BACKEND_GEMINI)
 	}
-	if len(resp.Choices) == 0 {
-		return nil, NewNonRetriableError(errors.New("no choices in response"))
+	client, err := genai.NewClient(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a client: %w", err)
 	}
-	answer := resp.Choices[0].Message.Content
-	log.Trace().Msgf("Got answer:\n%s", answer)
-	return &Solution{
-		Lang:         lang,
-		Prompt:       prompt,
-		Answer:       answer,
-		TypedCode:    extractCode(answer),
-		Model:        resp.Model,
-		SolvedAt:     time.Now(),
-		Latency:      latency,
-		PromptTokens: resp.Usage.PromptTokens,
-		OutputTokens: resp.Usage.CompletionTokens,
-	}, nil
+	return client, nil
 }
 
-func promptGoogle(q Question, lang, modelName, params string) (*Solution, error) {
+func promptGoogle(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.Error().Msgf("recovered: %v\n%s", err, debug.Stack())
 		}
 	}()
 
-	lang, prompt, err := generatePrompt(q, lang)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to make prompt: %w", err))
-	}
-	log.Debug().Msgf("Generated %d line(s) of code prompt", strings.Count(prompt, "\n"))
-	log.Trace().Msgf("Generated prompt:\n%s", prompt)
-
-	credJson, err := os.ReadFile(options.GeminiCredentialsFile)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to read credentials file: %w", err))
-	}
-	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
-		CredentialsJSON: credJson,
-		Scopes:          []string{"https://www.googleapis.com/auth/cloud-platform"},
-	})
+	client, err := sharedGoogleClient()
 	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to load credentials: %w", err))
+		return nil, NewFatalError(err)
 	}
 
 	ctx := context.Background()
-	config := &genai.ClientConfig{
-		Project:     options.GeminiProjectId,
-		Location:    options.GeminiRegion,
-		Backend:     genai.BackendVertexAI,
-		Credentials: creds,
-	}
-	client, err := genai.NewClient(ctx, config)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to create a client: %w", err))
-	}
 
 	t0 := time.Now()
-	resp, err := client.Models.GenerateContent(ctx, modelName, genai.Text(prompt), &genai.GenerateContentConfig{
+	contentConfig := &genai.GenerateContentConfig{
 		Temperature: genai.Ptr[float32](0.0),
 		TopP:        genai.Ptr[float32](0.0),
 		TopK:        genai.Ptr[float32](1.0),
-	})
+	}
+	if p.System != "" {
+		contentConfig.SystemInstruction = genai.NewContentFromText(p.System, genai.RoleUser)
+	}
+	if params.Temperature != nil {
+		contentConfig.Temperature = genai.Ptr(float32(*params.Temperature))
+	}
+	if params.TopP != nil {
+		contentConfig.TopP = genai.Ptr(float32(*params.TopP))
+	}
+	if params.MaxTokens != nil {
+		contentConfig.MaxOutputTokens = int32(*params.MaxTokens)
+	}
+	if params.Seed != nil {
+		contentConfig.Seed = genai.Ptr(int32(*params.Seed))
+	}
+	if params.ThinkingBudget != nil {
+		contentConfig.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(*params.ThinkingBudget))}
+	}
+	if options.Stream {
+		return streamGoogle(ctx, client, modelName, googleContents(p), contentConfig, p)
+	}
+	resp, err := client.Models.GenerateContent(ctx, modelName, googleContents(p), contentConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
@@ -401,10 +683,10 @@ func promptGoogle(q Question, lang, modelName, params string) (*Solution, error)
 		outputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
 	}
 	return &Solution{
-		Lang:         lang,
-		Prompt:       prompt,
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
 		Answer:       answer,
-		TypedCode:    extractCode(answer),
 		Model:        modelName,
 		SolvedAt:     time.Now(),
 		Latency:      latency,
@@ -413,56 +695,63 @@ func promptGoogle(q Question, lang, modelName, params string) (*Solution, error)
 	}, nil
 }
 
-func promptAnthropic(q Question, lang, modelName, params string) (*Solution, error) {
+func promptAnthropic(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) (*Solution, error) {
 	client := anthropic.NewClient(anthropic_option.WithAPIKey(options.ClaudeApiKey))
-	lang, prompt, err := generatePrompt(q, lang)
-	if err != nil {
-		return nil, NewFatalError(fmt.Errorf("failed to make prompt: %w", err))
+	messageParams := anthropicParams(p, modelName, params)
+	if options.Stream {
+		return streamAnthropic(client, messageParams, p)
 	}
-	log.Debug().Msgf("Generated %d line(s) of code prompt", strings.Count(prompt, "\n"))
-	log.Trace().Msgf("Generated prompt:\n%s", prompt)
 
-	var customParams struct {
-		MaxTokens int `json:"max_tokens"`
-		Thinking  struct {
-			Type         string `json:"type"`
-			BudgetTokens int    `json:"budget_tokens"`
-		} `json:"thinking"`
-	}
-	if params != "" {
-		err = json.Unmarshal([]byte(params), &customParams)
-		if err != nil {
-			return nil, NewFatalError(fmt.Errorf("failed to parse custom params: %w", err))
-		}
-		log.Debug().Msgf("using custom params: %+v", customParams)
+	t0 := time.Now()
+	resp, err := client.Messages.New(context.Background(), messageParams)
+	latency := time.Since(t0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send a message: %w", err)
 	}
 
+	log.Trace().Msgf("Got response:\n%+v", resp.Content)
+	return &Solution{
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
+		Answer:       anthropicAnswer(resp),
+		Model:        modelName,
+		SolvedAt:     time.Now(),
+		Latency:      latency,
+		PromptTokens: int(resp.Usage.InputTokens),
+		OutputTokens: int(resp.Usage.OutputTokens),
+	}, nil
+
+}
+
+func anthropicParams(p *ChatPrompt, modelName string, params leetgptsolver.ModelParams) anthropic.MessageNewParams {
 	messageParams := anthropic.MessageNewParams{
 		Model:       anthropic.Model(modelName),
 		Temperature: anthropic.Float(0.0),
-		Messages:    []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(prompt))},
-		MaxTokens:   4096,
+		Messages:    anthropicMessages(p),
+		MaxTokens:   leetgptsolver.ANTHROPIC_DEFAULT_MAX_TOKENS,
 	}
-	if customParams.MaxTokens > 0 {
-		messageParams.MaxTokens = int64(customParams.MaxTokens)
+	if p.System != "" {
+		messageParams.System = []anthropic.TextBlockParam{{Text: p.System}}
 	}
-	if customParams.Thinking.Type == "enabled" {
-		budgetTokens := int64(0)
-		if customParams.Thinking.BudgetTokens > 0 {
-			budgetTokens = int64(customParams.Thinking.BudgetTokens)
-		}
-		messageParams.Thinking = anthropic.ThinkingConfigParamOfEnabled(budgetTokens)
-		messageParams.Temperature = anthropic.Float(1.0)
+	if params.Temperature != nil {
+		messageParams.Temperature = anthropic.Float(*params.Temperature)
 	}
-
-	t0 := time.Now()
-	resp, err := client.Messages.New(context.Background(), messageParams)
-	latency := time.Since(t0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send a message: %w", err)
+	if params.TopP != nil {
+		messageParams.TopP = anthropic.Float(*params.TopP)
 	}
+	if params.MaxTokens != nil {
+		messageParams.MaxTokens = int64(*params.MaxTokens)
+	}
+	if budget := params.AnthropicThinkingBudget(); budget > 0 {
+		messageParams.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(budget))
+		// extended thinking requires the temperature of 1, params with another one are rejected by validation
+		messageParams.Temperature = anthropic.Float(1.0)
+	}
+	return messageParams
+}
 
-	log.Trace().Msgf("Got response:\n%+v", resp.Content)
+func anthropicAnswer(resp *anthropic.Message) string {
 	answer := ""
 	for _, block := range resp.Content {
 		switch block.Type {
@@ -473,19 +762,7 @@ func promptAnthropic(q Question, lang, modelName, params string) (*Solution, err
 			continue
 		}
 	}
-
-	return &Solution{
-		Lang:         lang,
-		Prompt:       prompt,
-		Answer:       answer,
-		TypedCode:    extractCode(answer),
-		Model:        modelName,
-		SolvedAt:     time.Now(),
-		Latency:      latency,
-		PromptTokens: int(resp.Usage.InputTokens),
-		OutputTokens: int(resp.Usage.OutputTokens),
-	}, nil
-
+	return answer
 }
 
 // very hackish
@@ -500,35 +777,202 @@ func geminiAnswer(r *genai.GenerateContentResponse) (string, error) {
 	return "", NewNonRetriableError(errors.New("no text in response"))
 }
 
-func generatePrompt(q Question, lang string) (string, string, error) {
-	prompt := options.PromptTemplate
-	if prompt == "" {
-		return "", "", errors.New("prompt_template is not set")
+// resultKey is the key of solutions and submissions of the model in problems.
+// Results of prompt variants (e.g. translated content) are stored separately from the default ones,
+// so they can be compared side by side
+func resultKey(modelName string) string {
+	key := modelName
+	if options.ContentLang != "" && options.ContentLang != ORIGINAL_CONTENT_LANG {
+		key += "#content_lang=" + options.ContentLang
+	}
+	if options.Template != "" {
+		key += "#template=" + options.Template
+	}
+	if len(options.FewShot) > 0 {
+		key += "#few_shot=" + strings.Join(fewShotSlugs(options.FewShot), ",")
+	}
+	return key
+}
+
+// vendors which accept images in prompts
+func vendorSupportsImages(vendor int) bool {
+	return vendor == leetgptsolver.MODEL_VENDOR_OPENAI ||
+		vendor == leetgptsolver.MODEL_VENDOR_AZURE ||
+		vendor == leetgptsolver.MODEL_VENDOR_GOOGLE ||
+		vendor == leetgptsolver.MODEL_VENDOR_ANTHROPIC
+}
+
+// openAiMessages maps the prompt onto chat messages: system, few-shot turns, then the question.
+// Also used for xAI and other vendors with the same API
+func openAiMessages(p *ChatPrompt) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{}
+	if p.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: p.System})
+	}
+	for _, shot := range p.Shots {
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: shot.User},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: shot.Assistant},
+		)
 	}
+	return append(messages, openAiUserMessage(p))
+}
 
-	selectedLang := lang
-	selectedSnippet := q.FindSnippet(selectedLang)
-	if selectedSnippet == "" {
-		return "", "", fmt.Errorf("failed to find code snippet for %s", selectedLang)
+func openAiUserMessage(p *ChatPrompt) openai.ChatCompletionMessage {
+	if len(p.Images) == 0 {
+		return openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: p.Text,
+		}
 	}
-	question := htmlToPlaintext(q.Data.Question.Content)
-	if replaceInplace(&prompt, "{language}", selectedLang) == 0 {
-		return "", "", errors.New("no {language} in prompt_template")
+
+	parts := []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: p.Text}}
+	for _, image := range p.Images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL: "data:" + image.MimeType + ";base64," + base64.StdEncoding.EncodeToString(image.Data),
+			},
+		})
 	}
-	if replaceInplace(&prompt, "{question}", question) == 0 {
-		return "", "", errors.New("no {question} in prompt_template")
+	return openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
 	}
-	if replaceInplace(&prompt, "{snippet}", selectedSnippet) == 0 {
-		return "", "", errors.New("no {snippet} in prompt_template")
+}
+
+func deepseekMessages(p *ChatPrompt) []deepseek.ChatCompletionMessage {
+	messages := []deepseek.ChatCompletionMessage{}
+	if p.System != "" {
+		messages = append(messages, deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleSystem, Content: p.System})
+	}
+	for _, shot := range p.Shots {
+		messages = append(messages,
+			deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleUser, Content: shot.User},
+			deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleAssistant, Content: shot.Assistant},
+		)
+	}
+	return append(messages, deepseek.ChatCompletionMessage{Role: deepseek.ChatMessageRoleUser, Content: p.Text})
+}
+
+// googleContents maps few-shot turns and the question onto contents. System message goes to the config
+func googleContents(p *ChatPrompt) []*genai.Content {
+	contents := []*genai.Content{}
+	for _, shot := range p.Shots {
+		contents = append(contents,
+			genai.NewContentFromText(shot.User, genai.RoleUser),
+			genai.NewContentFromText(shot.Assistant, genai.RoleModel),
+		)
 	}
+	return append(contents, googleUserContent(p))
+}
 
-	return selectedLang, prompt, nil
+func googleUserContent(p *ChatPrompt) *genai.Content {
+	parts := []*genai.Part{genai.NewPartFromText(p.Text)}
+	for _, image := range p.Images {
+		parts = append(parts, genai.NewPartFromBytes(image.Data, image.MimeType))
+	}
+	return genai.NewContentFromParts(parts, genai.RoleUser)
 }
 
-func replaceInplace(s *string, old, new string) int {
-	cnt := strings.Count(*s, old)
-	*s = strings.ReplaceAll(*s, old, new)
-	return cnt
+// anthropicMessages maps few-shot turns and the question onto messages. System message is a separate param
+func anthropicMessages(p *ChatPrompt) []anthropic.MessageParam {
+	messages := []anthropic.MessageParam{}
+	for _, shot := range p.Shots {
+		messages = append(messages,
+			anthropic.NewUserMessage(anthropic.NewTextBlock(shot.User)),
+			anthropic.NewAssistantMessage(anthropic.NewTextBlock(shot.Assistant)),
+		)
+	}
+	return append(messages, anthropicUserMessage(p))
+}
+
+func anthropicUserMessage(p *ChatPrompt) anthropic.MessageParam {
+	// anthropic recommends placing images before the text
+	blocks := []anthropic.ContentBlockParamUnion{}
+	for _, image := range p.Images {
+		blocks = append(blocks, anthropic.NewImageBlockBase64(image.MimeType, base64.StdEncoding.EncodeToString(image.Data)))
+	}
+	blocks = append(blocks, anthropic.NewTextBlock(p.Text))
+	return anthropic.NewUserMessage(blocks...)
+}
+
+func generatePrompt(q Question, lang string, withImages bool, shots []fewShot) (*ChatPrompt, error) {
+	tmpl, err := findPromptTemplate(options.Template, q.Category, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	content := q.Data.Question.Content
+	var images []PromptImage
+	if withImages && len(q.Images) > 0 {
+		images, err = loadPromptImages(q)
+		if err != nil {
+			log.Err(err).Msg("Failed to load images, prompting without them")
+			images = nil
+		} else {
+			content = replaceImageTags(content, images)
+		}
+	}
+	text, system, err := renderQuestion(tmpl, q, content, lang)
+	if err != nil {
+		return nil, err
+	}
+	chatPrompt := &ChatPrompt{
+		Lang:         lang,
+		Text:         text,
+		Images:       images,
+		System:       system,
+		TemplateName: tmpl.Name,
+		TemplateHash: tmpl.Hash(),
+	}
+
+	for _, shot := range shots {
+		slug := shot.Question.Data.Question.TitleSlug
+		if slug == q.Data.Question.TitleSlug {
+			log.Debug().Msgf("Skipping few-shot example %s: it is the question itself", slug)
+			continue
+		}
+		// demonstrations are rendered with the template for their own category
+		shotTmpl, err := findPromptTemplate(options.Template, shot.Question.Category, lang)
+		if err != nil {
+			return nil, err
+		}
+		shotText, _, err := renderQuestion(shotTmpl, shot.Question, shot.Question.Data.Question.Content, lang)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render few-shot example %s: %w", slug, err)
+		}
+		chatPrompt.Shots = append(chatPrompt.Shots, ChatShot{
+			Slug:      slug,
+			User:      shotText,
+			Assistant: "```" + lang + "\n" + strings.TrimSpace(shot.Code) + "\n```",
+		})
+	}
+
+	return chatPrompt, nil
+}
+
+// renderQuestion renders the template for the question with the given html content
+func renderQuestion(tmpl PromptTemplate, q Question, content, lang string) (string, string, error) {
+	snippet := q.FindSnippet(lang)
+	if snippet == "" {
+		return "", "", fmt.Errorf("failed to find code snippet for %s", lang)
+	}
+	question := htmlToPlaintext(content)
+	data := promptData{
+		Language:   lang,
+		Title:      q.Data.Question.Title,
+		Question:   question,
+		Snippet:    snippet,
+		Schema:     renderSchema(q, lang),
+		Difficulty: q.Data.Question.Difficulty,
+		Category:   q.Category,
+		Examples:   leetgptsolver.ParseExamples(question),
+	}
+	for _, tag := range q.Data.Question.TopicTags {
+		data.Tags = append(data.Tags, tag.Name)
+	}
+	return tmpl.render(data)
 }
 
 func htmlToPlaintext(s string) string {
@@ -557,13 +1001,3 @@ func htmlToPlaintext(s string) string {
 
 	return s
 }
-
-func extractCode(answer string) string {
-	re := regexp.MustCompile("(?ms)^```\\w*\\s*$(.+?)^```\\s*$")
-	m := re.FindStringSubmatch(answer)
-	if m == nil {
-		// maybe answer is the code itself?
-		return answer
-	}
-	return m[1]
-}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+)
+
+const testCompletion = `{"id":"1","object":"chat.completion","model":"served-model",` +
+	`"choices":[{"index":0,"message":{"role":"assistant","content":"answer"},"finish_reason":"stop"}],` +
+	`"usage":{"prompt_tokens":11,"completion_tokens":7,"total_tokens":18}}`
+
+const testCompletionStream = `data: {"id":"1","object":"chat.completion.chunk","model":"served-model","choices":[{"index":0,"delta":{"role":"assistant","content":"ans"}}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"served-model","choices":[{"index":0,"delta":{"content":"wer"},"finish_reason":"stop"}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"served-model","choices":[],"usage":{"prompt_tokens":11,"completion_tokens":7,"total_tokens":18}}
+
+data: [DONE]
+
+`
+
+// stand-in of an openai compatible api, it records the last request
+type standIn struct {
+	path  string
+	query url.Values
+	body  map[string]any
+}
+
+func (s *standIn) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.path = r.URL.Path
+		s.query = r.URL.Query()
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read the request: %v", err)
+		}
+		s.body = map[string]any{}
+		err = json.Unmarshal(data, &s.body)
+		if err != nil {
+			t.Errorf("failed to parse the request: %v", err)
+		}
+		if s.body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(testCompletionStream))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testCompletion))
+	}))
+}
+
+func TestCompatiblePrompters(t *testing.T) {
+	// base urls, azure settings and streaming are changed by the cases
+	oldOptions := options
+	t.Cleanup(func() { options = oldOptions })
+
+	tests := []struct {
+		name string
+		// points the prompter to the stand-in
+		setup         func(url string)
+		prompter      prompterFunc
+		modelName     string
+		params        string
+		vendor        int
+		stream        bool
+		expectedPath  string
+		expectedQuery url.Values
+		// expected fields of the request, nil means the field is absent
+		expectedFields map[string]any
+	}{
+		{
+			name:           "mistral max_tokens without seed",
+			setup:          func(url string) { options.MistralBaseUrl = url },
+			prompter:       promptMistral,
+			modelName:      "codestral-latest",
+			params:         `{"max_tokens":1000,"temperature":0.2}`,
+			vendor:         leetgptsolver.MODEL_VENDOR_MISTRAL,
+			expectedPath:   "/chat/completions",
+			expectedFields: map[string]any{"model": "codestral-latest", "max_tokens": 1000.0, "max_completion_tokens": nil, "seed": nil},
+		},
+		{
+			name:           "mistral stream",
+			setup:          func(url string) { options.MistralBaseUrl = url },
+			prompter:       promptMistral,
+			modelName:      "codestral-latest",
+			params:         `{"max_tokens":1000}`,
+			vendor:         leetgptsolver.MODEL_VENDOR_MISTRAL,
+			stream:         true,
+			expectedPath:   "/chat/completions",
+			expectedFields: map[string]any{"max_tokens": 1000.0, "max_completion_tokens": nil, "seed": nil, "stream": true},
+		},
+		{
+			name:           "qwen keeps seed",
+			setup:          func(url string) { options.QwenBaseUrl = url },
+			prompter:       promptQwen,
+			modelName:      "qwen3-coder-plus",
+			params:         `{"max_tokens":500,"seed":7}`,
+			vendor:         leetgptsolver.MODEL_VENDOR_QWEN,
+			expectedPath:   "/chat/completions",
+			expectedFields: map[string]any{"max_tokens": 500.0, "max_completion_tokens": nil, "seed": 7.0},
+		},
+		{
+			name:           "moonshot without seed",
+			setup:          func(url string) { options.MoonshotBaseUrl = url },
+			prompter:       promptMoonshot,
+			modelName:      "kimi-k2-0905-preview",
+			vendor:         leetgptsolver.MODEL_VENDOR_MOONSHOT,
+			expectedPath:   "/chat/completions",
+			expectedFields: map[string]any{"model": "kimi-k2-0905-preview", "seed": nil},
+		},
+		{
+			name: "azure deployment",
+			setup: func(url string) {
+				options.AzureOpenaiEndpoint = url
+				options.AzureOpenaiApiVersion = ""
+				options.AzureOpenaiDeployments = map[string]string{"gpt-4o": "my-deployment"}
+			},
+			prompter:       promptAzure,
+			modelName:      "gpt-4o",
+			params:         `{"max_tokens":1000}`,
+			vendor:         leetgptsolver.MODEL_VENDOR_AZURE,
+			expectedPath:   "/openai/deployments/my-deployment/chat/completions",
+			expectedQuery:  url.Values{"api-version": {AZURE_OPENAI_API_VERSION}},
+			expectedFields: map[string]any{"max_completion_tokens": 1000.0, "max_tokens": nil},
+		},
+		{
+			name: "azure model without a deployment and api version",
+			setup: func(url string) {
+				options.AzureOpenaiEndpoint = url
+				options.AzureOpenaiApiVersion = "2025-01-01-preview"
+				options.AzureOpenaiDeployments = nil
+			},
+			prompter:      promptAzure,
+			modelName:     "gpt-4.1",
+			vendor:        leetgptsolver.MODEL_VENDOR_AZURE,
+			expectedPath:  "/openai/deployments/gpt-4.1/chat/completions",
+			expectedQuery: url.Values{"api-version": {"2025-01-01-preview"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s standIn
+			server := s.serve(t)
+			defer server.Close()
+			test.setup(server.URL)
+			options.Stream = test.stream
+			params, err := leetgptsolver.ParseModelParams(test.params, test.vendor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			solution, err := test.prompter(&ChatPrompt{Lang: "python3", Text: "Solve the problem"}, test.modelName, params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.path != test.expectedPath {
+				t.Errorf("expected path: %s, got: %s", test.expectedPath, s.path)
+			}
+			for key, values := range test.expectedQuery {
+				if s.query.Get(key) != values[0] {
+					t.Errorf("expected %s: %s, got: %s", key, values[0], s.query.Get(key))
+				}
+			}
+			for field, expected := range test.expectedFields {
+				if actual, ok := s.body[field]; actual != expected || (expected == nil && ok) {
+					t.Errorf("expected %s: %v, got: %v", field, expected, actual)
+				}
+			}
+			if solution.Answer != "answer" || solution.Model != "served-model" {
+				t.Errorf("expected answer from served-model, got %q from %s", solution.Answer, solution.Model)
+			}
+			if solution.PromptTokens != 11 || solution.OutputTokens != 7 {
+				t.Errorf("expected usage 11/7, got: %d/%d", solution.PromptTokens, solution.OutputTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/rs/zerolog/log"
+)
+
+// failure classes, in the order of report columns
+const (
+	FAILURE_WRONG_ANSWER      = "Wrong Answer"
+	FAILURE_TIME_LIMIT        = "Time Limit Exceeded"
+	FAILURE_MEMORY_LIMIT      = "Memory Limit Exceeded"
+	FAILURE_OUTPUT_LIMIT      = "Output Limit Exceeded"
+	FAILURE_RUNTIME_ERROR     = "Runtime Error"
+	FAILURE_COMPILE_ERROR     = "Compile Error"
+	FAILURE_CODE_TOO_LONG     = "Code Too Long"
+	FAILURE_REJECTED          = "Rejected"
+	FAILURE_EXTRACTION        = "Extraction Failed"
+	FAILURE_OTHER             = "Other"
+	FAILURE_NOT_SUBMITTED     = "Not Submitted"
+	STATUS_ACCEPTED           = "Accepted"
+	REPORT_UNKNOWN_DIFFICULTY = "Unknown"
+)
+
+var failureClasses = []string{
+	FAILURE_WRONG_ANSWER,
+	FAILURE_TIME_LIMIT,
+	FAILURE_MEMORY_LIMIT,
+	FAILURE_OUTPUT_LIMIT,
+	FAILURE_RUNTIME_ERROR,
+	FAILURE_COMPILE_ERROR,
+	FAILURE_CODE_TOO_LONG,
+	FAILURE_REJECTED,
+	FAILURE_EXTRACTION,
+	FAILURE_OTHER,
+}
+
+var difficultyOrder = []string{"Easy", "Medium", "Hard", REPORT_UNKNOWN_DIFFICULTY}
+
+type failureGroup struct {
+	Model      string
+	Lang       string
+	Difficulty string
+}
+
+type failureCounts struct {
+	Solutions int
+	Accepted  int
+	Failures  map[string]int
+}
+
+// classifyFailure returns the failure class of a solution, FAILURE_NOT_SUBMITTED for solutions which
+// were neither submitted nor failed on extraction, or STATUS_ACCEPTED
+func classifyFailure(s Solution, subm Submission, submitted bool) string {
+	if submitted && subm.CheckResponse.StatusMsg == STATUS_ACCEPTED {
+		return STATUS_ACCEPTED
+	}
+	// code which was not found in the answer fails for that reason, whatever leetcode said about it
+	if s.TypedCode == "" || s.ExtractStrategy == leetgptsolver.EXTRACT_RAW || s.ExtractStrategy == leetgptsolver.EXTRACT_NONE {
+		return FAILURE_EXTRACTION
+	}
+	if !submitted {
+		return FAILURE_NOT_SUBMITTED
+	}
+	msg := subm.CheckResponse.StatusMsg
+	switch {
+	case msg == STATUS_REJECTED:
+		return FAILURE_REJECTED
+	case strings.HasPrefix(msg, "Your code is too long"):
+		return FAILURE_CODE_TOO_LONG
+	case slices.Contains(failureClasses, msg):
+		return msg
+	}
+	return FAILURE_OTHER
+}
+
+func reportFailures(args []string, lang, modelName string) {
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+
+	groups := map[failureGroup]*failureCounts{}
+	errorsCnt := 0
+	for _, file := range files {
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			log.Err(err).Msg("Failed to read the problem")
+			errorsCnt += 1
+			continue
+		}
+		difficulty := problem.Question.Data.Question.Difficulty
+		if difficulty == "" {
+			difficulty = REPORT_UNKNOWN_DIFFICULTY
+		}
+		for model, solutionLang := range problemSolutionKeys(problem) {
+			if modelName != "" && model != modelName {
+				continue
+			}
+			for _, l := range solutionLang {
+				if lang != "" && l != lang {
+					continue
+				}
+				solution, _ := problem.GetSolution(model, l)
+				subm, submitted := problem.GetSubmission(model, l)
+				class := classifyFailure(solution, subm, submitted)
+				if class == FAILURE_NOT_SUBMITTED {
+					continue
+				}
+				group := failureGroup{Model: model, Lang: l, Difficulty: difficulty}
+				counts, ok := groups[group]
+				if !ok {
+					counts = &failureCounts{Failures: map[string]int{}}
+					groups[group] = counts
+				}
+				counts.Solutions += 1
+				if class == STATUS_ACCEPTED {
+					counts.Accepted += 1
+				} else {
+					counts.Failures[class] += 1
+				}
+			}
+		}
+	}
+
+	keys := make([]failureGroup, 0, len(groups))
+	for g := range groups {
+		keys = append(keys, g)
+	}
+	slices.SortFunc(keys, func(a, b failureGroup) int {
+		if c := strings.Compare(a.Model, b.Model); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Lang, b.Lang); c != 0 {
+			return c
+		}
+		return slices.Index(difficultyOrder, a.Difficulty) - slices.Index(difficultyOrder, b.Difficulty)
+	})
+
+	fmt.Println(strings.Join(append([]string{"Model", "Lang", "Difficulty", "Solutions", "Accepted"}, failureClasses...), SEPARATOR))
+	for _, g := range keys {
+		counts := groups[g]
+		row := []string{g.Model, g.Lang, g.Difficulty, fmt.Sprint(counts.Solutions), fmt.Sprint(counts.Accepted)}
+		for _, class := range failureClasses {
+			row = append(row, fmt.Sprint(counts.Failures[class]))
+		}
+		fmt.Println(strings.Join(row, SEPARATOR))
+	}
+	if errorsCnt > 0 {
+		log.Warn().Msgf("Failed to read %d problems", errorsCnt)
+	}
+}
+
+// problemSolutionKeys returns languages of solutions by result key, from both old and new solution maps
+func problemSolutionKeys(p Problem) map[string][]string {
+	keys := map[string][]string{}
+	for model, byLang := range p.SolutionsV2 {
+		for lang := range byLang {
+			keys[model] = append(keys[model], lang)
+		}
+	}
+	for model, sol := range p.Solutions {
+		if !slices.Contains(keys[model], sol.Lang) {
+			keys[model] = append(keys[model], sol.Lang)
+		}
+	}
+	return keys
+}
+
+type latencyGroup struct {
+	Model string
+	Lang  string
+}
+
+type latencySamples struct {
+	latency          []time.Duration
+	timeToFirstToken []time.Duration
+	tokensPerSecond  []float64
+}
+
+// reportLatency prints medians of latency metrics per model and language. Time to first token and
+// tokens per second are known only for streamed answers
+func reportLatency(args []string, lang, modelName string) {
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+
+	groups := map[latencyGroup]*latencySamples{}
+	errorsCnt := 0
+	for _, file := range files {
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			log.Err(err).Msg("Failed to read the problem")
+			errorsCnt += 1
+			continue
+		}
+		for model, solutionLang := range problemSolutionKeys(problem) {
+			if modelName != "" && model != modelName {
+				continue
+			}
+			for _, l := range solutionLang {
+				if lang != "" && l != lang {
+					continue
+				}
+				solution, _ := problem.GetSolution(model, l)
+				if solution.Latency == 0 {
+					// batch answers
+					continue
+				}
+				group := latencyGroup{Model: model, Lang: l}
+				samples, ok := groups[group]
+				if !ok {
+					samples = &latencySamples{}
+					groups[group] = samples
+				}
+				samples.latency = append(samples.latency, solution.Latency)
+				if solution.TimeToFirstToken > 0 {
+					samples.timeToFirstToken = append(samples.timeToFirstToken, solution.TimeToFirstToken)
+				}
+				if solution.TokensPerSecond > 0 {
+					samples.tokensPerSecond = append(samples.tokensPerSecond, solution.TokensPerSecond)
+				}
+			}
+		}
+	}
+
+	keys := make([]latencyGroup, 0, len(groups))
+	for g := range groups {
+		keys = append(keys, g)
+	}
+	slices.SortFunc(keys, func(a, b latencyGroup) int {
+		if c := strings.Compare(a.Model, b.Model); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Lang, b.Lang)
+	})
+
+	fmt.Println(strings.Join([]string{"Model", "Lang", "Solutions", "Latency", "Streamed", "TimeToFirstToken", "TokensPerSecond"}, SEPARATOR))
+	for _, g := range keys {
+		samples := groups[g]
+		row := []string{
+			g.Model,
+			g.Lang,
+			fmt.Sprint(len(samples.latency)),
+			fmt.Sprintf("%0.1f", median(samples.latency).Seconds()),
+			fmt.Sprint(len(samples.timeToFirstToken)),
+			fmt.Sprintf("%0.1f", median(samples.timeToFirstToken).Seconds()),
+			fmt.Sprintf("%0.1f", median(samples.tokensPerSecond)),
+		}
+		fmt.Println(strings.Join(row, SEPARATOR))
+	}
+	if errorsCnt > 0 {
+		log.Warn().Msgf("Failed to read %d problems", errorsCnt)
+	}
+}
+
+func median[T time.Duration | float64](values []T) T {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	if len(sorted)%2 == 1 {
+		return sorted[len(sorted)/2]
+	}
+	return (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+)
+
+// renderSchema renders the table definitions of a database or pandas question for the given language.
+// Leetcode provides mysql schemas only, so other sql dialects are derived from them.
+func renderSchema(q Question, lang string) string {
+	if lang == "pythondata" {
+		return strings.Join(q.Data.Question.DataSchemas, "\n")
+	}
+
+	var statements []string
+	for _, stmt := range q.Data.Question.MysqlSchemas {
+		// we want only DDL, not the test data
+		if !leetgptsolver.IsCreateTable(stmt) {
+			continue
+		}
+		statements = append(statements, leetgptsolver.ConvertMysqlDDL(strings.TrimSpace(stmt), lang)+";")
+	}
+
+	return strings.Join(statements, "\n")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/browserutils/kooky"
+	_ "github.com/browserutils/kooky/browser/chrome"
+	_ "github.com/browserutils/kooky/browser/firefox"
+	"github.com/browserutils/kooky/browser/netscape"
+	"github.com/rs/zerolog/log"
+)
+
+const SESSION_COOKIE = "LEETCODE_SESSION"
+const CSRF_COOKIE = "csrftoken"
+
+// sessionSource provides leetcode session cookies. Expired cookies are skipped unless includeExpired is set
+type sessionSource interface {
+	Name() string
+	Cookies(ctx context.Context, includeExpired bool) ([]*http.Cookie, error)
+}
+
+// explicitSessionSource uses cookie values set in the config or environment
+type explicitSessionSource struct {
+	session   string
+	csrfToken string
+	origin    string
+}
+
+func (s explicitSessionSource) Name() string {
+	return "explicit session from " + s.origin
+}
+
+func (s explicitSessionSource) Cookies(ctx context.Context, includeExpired bool) ([]*http.Cookie, error) {
+	if s.csrfToken == "" {
+		log.Warn().Msg("csrftoken is not set, leetcode will likely reject POST requests")
+	}
+	cookies := []*http.Cookie{{Name: SESSION_COOKIE, Value: s.session, Domain: leetcodeUrl.Host, Path: "/"}}
+	if s.csrfToken != "" {
+		cookies = append(cookies, &http.Cookie{Name: CSRF_COOKIE, Value: s.csrfToken, Domain: leetcodeUrl.Host, Path: "/"})
+	}
+	return cookies, nil
+}
+
+// cookiesFileSessionSource reads cookies from a file in the Netscape cookies.txt format,
+// as exported by browser extensions or curl
+type cookiesFileSessionSource struct {
+	path string
+}
+
+func (s cookiesFileSessionSource) Name() string {
+	return "cookies file " + s.path
+}
+
+func (s cookiesFileSessionSource) Cookies(ctx context.Context, includeExpired bool) ([]*http.Cookie, error) {
+	kookies, _, err := netscape.ReadCookies(ctx, s.path, cookieFilters(includeExpired)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies file: %w", err)
+	}
+	return httpCookies(kookies), nil
+}
+
+// browserSessionSource reads cookies from a browser profile. Empty profile means the default profile
+type browserSessionSource struct {
+	browser string
+	profile string
+}
+
+func (s browserSessionSource) Name() string {
+	if s.profile == "" {
+		return s.browser + " (default profile)"
+	}
+	return s.browser + " (profile " + s.profile + ")"
+}
+
+func (s browserSessionSource) Cookies(ctx context.Context, includeExpired bool) ([]*http.Cookie, error) {
+	profiles := []string{}
+	for _, cookieStore := range kooky.FindAllCookieStores(ctx) {
+		defer cookieStore.Close()
+		if ok, _ := fileExists(cookieStore.FilePath()); !ok {
+			// skip non-existing cookie stores
+			continue
+		}
+		log.Trace().Msgf("Found cookie store for %s: %s (profile: %s, default: %v)", cookieStore.Browser(), cookieStore.FilePath(), cookieStore.Profile(), cookieStore.IsDefaultProfile())
+		if cookieStore.Browser() != s.browser {
+			continue
+		}
+		profiles = append(profiles, cookieStore.Profile())
+		if (s.profile == "" && !cookieStore.IsDefaultProfile()) || (s.profile != "" && cookieStore.Profile() != s.profile) {
+			continue
+		}
+
+		kookies, err := cookieStore.TraverseCookies(cookieFilters(includeExpired)...).ReadAllCookies(ctx)
+		if err != nil {
+			log.Err(err).Msgf("failed to read cookies from %s", cookieStore.FilePath())
+			continue
+		}
+		log.Debug().Msgf("using cookies from %s", cookieStore.FilePath())
+		return httpCookies(kookies), nil
+	}
+
+	if len(profiles) > 0 {
+		return nil, fmt.Errorf("no suitable %s cookie store found, available profiles: %s", s.browser, strings.Join(profiles, ", "))
+	}
+	return nil, fmt.Errorf("no %s cookie stores found", s.browser)
+}
+
+// cookieFilters selects leetcode cookies, valid ones only unless includeExpired is set
+func cookieFilters(includeExpired bool) []kooky.Filter {
+	filters := []kooky.Filter{kooky.DomainHasSuffix(leetcodeUrl.Host)}
+	if !includeExpired {
+		filters = append(filters, kooky.Valid)
+	}
+	return filters
+}
+
+func httpCookies(kookies []*kooky.Cookie) []*http.Cookie {
+	cookies := []*http.Cookie{}
+	for _, c := range kookies {
+		cookies = append(cookies, &c.Cookie)
+	}
+	return cookies
+}
+
+// activeSessionSource picks the session source from options. Explicit session has the highest priority,
+// then the cookies file, then the browser profile
+func activeSessionSource() sessionSource {
+	session, csrfToken, origin := options.LeetcodeSession, options.LeetcodeCsrfToken, "config"
+	if session == "" {
+		session, csrfToken, origin = os.Getenv(SESSION_COOKIE), os.Getenv("LEETCODE_CSRFTOKEN"), "environment"
+	}
+	if session != "" {
+		return explicitSessionSource{session: session, csrfToken: csrfToken, origin: origin}
+	}
+	if options.CookiesFile != "" {
+		return cookiesFileSessionSource{path: options.CookiesFile}
+	}
+	browser := options.Browser
+	if browser == "" {
+		browser = "firefox"
+	}
+	return browserSessionSource{browser: browser, profile: options.BrowserProfile}
+}
+
+// sessionExpiresAt returns the session expiration time, either from the cookie itself
+// or from the session token (which is a JWT). Zero time means unknown
+func sessionExpiresAt(c *http.Cookie) time.Time {
+	if !c.Expires.IsZero() {
+		return c.Expires
+	}
+
+	parts := strings.Split(c.Value, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp         int64 `json:"exp"`
+		ExpiredTime int64 `json:"expired_time_"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}
+	}
+	if claims.Exp > 0 {
+		return time.Unix(claims.Exp, 0)
+	}
+	if claims.ExpiredTime > 0 {
+		return time.Unix(claims.ExpiredTime, 0)
+	}
+	return time.Time{}
+}
+
+// loginStatus prints the active session source, the session expiration and the signed in user
+func loginStatus() {
+	source := activeSessionSource()
+	fmt.Printf("Session source: %s\n", source.Name())
+
+	// expired cookies are read too, to tell them apart from missing ones
+	cookies, err := source.Cookies(context.TODO(), true)
+	if err != nil {
+		log.Err(err).Msg("failed to load session cookies")
+		return
+	}
+	for _, name := range []string{SESSION_COOKIE, CSRF_COOKIE} {
+		idx := slices.IndexFunc(cookies, func(c *http.Cookie) bool { return c.Name == name })
+		if idx == -1 {
+			fmt.Printf("%s: not found\n", name)
+			continue
+		}
+		expiresAt := sessionExpiresAt(cookies[idx])
+		switch {
+		case expiresAt.IsZero():
+			fmt.Printf("%s: found, expiration is unknown\n", name)
+		case expiresAt.Before(time.Now()):
+			fmt.Printf("%s: found, expired at %s\n", name, humanizeTime(expiresAt))
+		default:
+			fmt.Printf("%s: found, expires at %s (in %s)\n", name, humanizeTime(expiresAt), time.Until(expiresAt).Round(time.Minute))
+		}
+	}
+
+	userStatus, err := LoadUserStatus()
+	if err != nil {
+		log.Err(err).Msg("failed to get user status from leetcode")
+		return
+	}
+	if userStatus.Username == "" {
+		fmt.Println("Username: none, not signed in")
+		return
+	}
+	fmt.Printf("Username: %s\n", userStatus.Username)
+}
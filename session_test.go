@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCookiesFileExpiredCookies(t *testing.T) {
+	oldUrl := leetcodeUrl
+	t.Cleanup(func() { leetcodeUrl = oldUrl })
+	leetcodeUrl, _ = url.Parse("https://leetcode.com/")
+
+	expired, valid := time.Now().Add(-time.Hour).Unix(), time.Now().Add(time.Hour).Unix()
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	content := fmt.Sprintf("# Netscape HTTP Cookie File\n"+
+		".leetcode.com\tTRUE\t/\tTRUE\t%d\tLEETCODE_SESSION\tsession\n"+
+		".leetcode.com\tTRUE\t/\tTRUE\t%d\tcsrftoken\ttoken\n"+
+		".example.com\tTRUE\t/\tTRUE\t%d\tother\tvalue\n", expired, valid, valid)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		includeExpired bool
+		expected       []string
+	}{
+		{includeExpired: false, expected: []string{CSRF_COOKIE}},
+		{includeExpired: true, expected: []string{SESSION_COOKIE, CSRF_COOKIE}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("include expired %v", test.includeExpired), func(t *testing.T) {
+			cookies, err := cookiesFileSessionSource{path: path}.Cookies(context.Background(), test.includeExpired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, c := range cookies {
+				names = append(names, c.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("expected cookies: %v, got: %v", test.expected, names)
+			}
+		})
+	}
+}
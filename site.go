@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Site describes a leetcode site and differences between sites
+type Site struct {
+	Name string
+	Url  *url.URL
+	// question has translated title and content
+	HasTranslations bool
+	// solution articles and discussions api used to detect question creation date
+	HasUgcApi bool
+	// problemset is listed with the older problemsetQuestionList api instead of questionList
+	HasLegacyQuestionList bool
+	// questions of non-default sites are stored with a suffix, so they can coexist with leetcode.com ones
+	FileSuffix string
+}
+
+const DEFAULT_SITE = "leetcode.com"
+
+var sites = []*Site{
+	{
+		Name:      "leetcode.com",
+		Url:       mustParseUrl("https://leetcode.com/"),
+		HasUgcApi: true,
+	},
+	{
+		Name:                  "leetcode.cn",
+		Url:                   mustParseUrl("https://leetcode.cn/"),
+		HasTranslations:       true,
+		HasLegacyQuestionList: true,
+		FileSuffix:            ".cn",
+	},
+}
+
+// site selected by options, all requests go to this site
+var leetcodeSite *Site
+
+func initSite() {
+	site, err := siteByName(options.Site)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to select leetcode site")
+	}
+	leetcodeSite = site
+	leetcodeUrl = site.Url
+	leetcodeGraphqlUrl = site.Url.JoinPath("graphql")
+}
+
+func mustParseUrl(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse url %s: %v. This is a bug", s, err))
+	}
+	return u
+}
+
+// siteByName finds a site by its name, empty name means the default site
+func siteByName(name string) (*Site, error) {
+	if name == "" {
+		name = DEFAULT_SITE
+	}
+	for _, site := range sites {
+		if site.Name == name {
+			return site, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown site: %s", name)
+}
+
+func (s *Site) ProblemUrl(slug string) string {
+	return s.Url.JoinPath("problems", slug).String() + "/"
+}
+
+func (s *Site) SubmitUrl(slug string) string {
+	return s.Url.JoinPath("problems", slug, "submit").String() + "/"
+}
+
+func (s *Site) SubmissionCheckUrl(submissionId uint64) string {
+	return s.Url.JoinPath("submissions", "detail", fmt.Sprint(submissionId), "check").String() + "/"
+}
+
+func (s *Site) ProblemsApiUrl(category string) string {
+	return s.Url.JoinPath("api", "problems", category).String() + "/"
+}
+
+func (s *Site) ProblemFilename(slug string) string {
+	return slug + s.FileSuffix + ".json"
+}
+
+// SlugFromFilename is the reverse of ProblemFilename
+func (s *Site) SlugFromFilename(file string) string {
+	name := filepath.Base(file)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimSuffix(name, s.FileSuffix)
+}
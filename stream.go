@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/cohesion-org/deepseek-go"
+	"github.com/rs/zerolog/log"
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+)
+
+// how often progress of a streamed answer is logged in verbose mode
+const STREAM_PROGRESS_INTERVAL = 5 * time.Second
+
+// streamStats measures a streamed answer
+type streamStats struct {
+	start      time.Time
+	firstToken time.Time
+	lastReport time.Time
+	chunks     int
+	chars      int
+}
+
+func newStreamStats() *streamStats {
+	now := time.Now()
+	return &streamStats{start: now, lastReport: now}
+}
+
+// add records a chunk of the answer, reasoning included
+func (s *streamStats) add(text string) {
+	if text == "" {
+		return
+	}
+	now := time.Now()
+	if s.firstToken.IsZero() {
+		s.firstToken = now
+		log.Debug().Msgf("First token in %0.1f second(s)", now.Sub(s.start).Seconds())
+	}
+	s.chunks += 1
+	s.chars += len(text)
+	if now.Sub(s.lastReport) >= STREAM_PROGRESS_INTERVAL {
+		s.lastReport = now
+		log.Debug().Msgf("Streaming: %d chunk(s), %d char(s) in %0.1f second(s)", s.chunks, s.chars, now.Sub(s.start).Seconds())
+	}
+}
+
+// apply sets latency metrics of the solution, output tokens must be set before
+func (s *streamStats) apply(solution *Solution) {
+	end := time.Now()
+	solution.Latency = end.Sub(s.start)
+	if s.firstToken.IsZero() {
+		return
+	}
+	solution.TimeToFirstToken = s.firstToken.Sub(s.start)
+	if generation := end.Sub(s.firstToken).Seconds(); generation > 0 && solution.OutputTokens > 0 {
+		solution.TokensPerSecond = float64(solution.OutputTokens) / generation
+	}
+}
+
+// streamOpenAi is used for openai and openai compatible apis
+func streamOpenAi(client *openai.Client, request openai.ChatCompletionRequest, p *ChatPrompt) (*Solution, error) {
+	request.Stream = true
+	request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stats := newStreamStats()
+	stream, err := client.CreateChatCompletionStream(context.Background(), request)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var answer strings.Builder
+	solution := &Solution{
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
+		Model:        request.Model,
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive a stream chunk: %w", err)
+		}
+		if resp.Model != "" {
+			solution.Model = resp.Model
+		}
+		if resp.Usage != nil {
+			solution.PromptTokens = resp.Usage.PromptTokens
+			solution.OutputTokens = resp.Usage.CompletionTokens
+		}
+		for _, choice := range resp.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			stats.add(choice.Delta.ReasoningContent + choice.Delta.Content)
+			answer.WriteString(choice.Delta.Content)
+		}
+	}
+	if stats.chunks == 0 {
+		return nil, NewNonRetriableError(errors.New("no choices in response"))
+	}
+	solution.Answer = answer.String()
+	solution.SolvedAt = time.Now()
+	stats.apply(solution)
+	log.Trace().Msgf("Got answer:\n%s", solution.Answer)
+	return solution, nil
+}
+
+func streamDeepseek(client *deepseek.Client, request deepseek.ChatCompletionRequest, p *ChatPrompt) (*Solution, error) {
+	stats := newStreamStats()
+	stream, err := client.CreateChatCompletionStream(context.Background(), &deepseek.StreamChatCompletionRequest{
+		Stream:        true,
+		StreamOptions: deepseek.StreamOptions{IncludeUsage: true},
+		Model:         request.Model,
+		Messages:      request.Messages,
+		Temperature:   request.Temperature,
+		TopP:          request.TopP,
+		MaxTokens:     request.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var answer strings.Builder
+	solution := &Solution{
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
+		Model:        request.Model,
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive a stream chunk: %w", err)
+		}
+		if resp.Model != "" {
+			solution.Model = resp.Model
+		}
+		if resp.Usage != nil {
+			solution.PromptTokens = resp.Usage.PromptTokens
+			solution.OutputTokens = resp.Usage.CompletionTokens
+		}
+		for _, choice := range resp.Choices {
+			stats.add(choice.Delta.ReasoningContent + choice.Delta.Content)
+			answer.WriteString(choice.Delta.Content)
+		}
+	}
+	if stats.chunks == 0 {
+		return nil, NewNonRetriableError(errors.New("no choices in response"))
+	}
+	solution.Answer = answer.String()
+	solution.SolvedAt = time.Now()
+	stats.apply(solution)
+	log.Trace().Msgf("Got answer:\n%s", solution.Answer)
+	return solution, nil
+}
+
+func streamGoogle(ctx context.Context, client *genai.Client, modelName string, contents []*genai.Content, config *genai.GenerateContentConfig, p *ChatPrompt) (*Solution, error) {
+	stats := newStreamStats()
+	var answer strings.Builder
+	solution := &Solution{
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
+		Model:        modelName,
+	}
+	for resp, err := range client.Models.GenerateContentStream(ctx, modelName, contents, config) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate content: %w", err)
+		}
+		text := resp.Text()
+		stats.add(text)
+		answer.WriteString(text)
+		if resp.UsageMetadata != nil {
+			solution.PromptTokens = int(resp.UsageMetadata.PromptTokenCount)
+			solution.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+		}
+	}
+	solution.Answer = strings.TrimSpace(answer.String())
+	if solution.Answer == "" {
+		return nil, NewNonRetriableError(errors.New("no text in response"))
+	}
+	solution.SolvedAt = time.Now()
+	stats.apply(solution)
+	log.Trace().Msgf("Got answer:\n%s", solution.Answer)
+	return solution, nil
+}
+
+func streamAnthropic(client anthropic.Client, params anthropic.MessageNewParams, p *ChatPrompt) (*Solution, error) {
+	stats := newStreamStats()
+	stream := client.Messages.NewStreaming(context.Background(), params)
+	defer stream.Close()
+
+	message := anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		err := message.Accumulate(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to accumulate the message: %w", err)
+		}
+		if event.Type == "content_block_delta" {
+			stats.add(event.Delta.Thinking + event.Delta.Text)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to send a message: %w", err)
+	}
+
+	log.Trace().Msgf("Got response:\n%+v", message.Content)
+	solution := &Solution{
+		Lang:         p.Lang,
+		Prompt:       p.Text,
+		PromptImages: len(p.Images),
+		Answer:       anthropicAnswer(&message),
+		Model:        string(params.Model),
+		SolvedAt:     time.Now(),
+		PromptTokens: int(message.Usage.InputTokens),
+		OutputTokens: int(message.Usage.OutputTokens),
+	}
+	stats.apply(solution)
+	return solution, nil
+}
@@ -2,16 +2,16 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+	leetgptsolver "whisk/leetgptsolver/pkg"
 
 	"github.com/rs/zerolog/log"
-	"golang.org/x/time/rate"
 )
 
 type InvalidCodeError struct {
@@ -22,7 +22,31 @@ func NewInvalidCodeError(err error) error {
 	return InvalidCodeError{err}
 }
 
-var leetcodeLimiter *rate.Limiter
+// variants of the submitted code, they differ only in the comment added before the code
+const (
+	CODE_VARIANT_PLAIN    = "plain"
+	CODE_VARIANT_METADATA = "metadata"
+	// the comment without the model name and time
+	CODE_VARIANT_NEUTRAL = "neutral"
+)
+
+// what to do when a submission is rejected with 403
+const (
+	ON_REJECTION_NONE    = "none"
+	ON_REJECTION_STRIP   = "strip"
+	ON_REJECTION_NEUTRAL = "neutral"
+)
+
+// status of submissions leetcode refused with a json 403, they are recorded unfinished so they are submitted again
+const STATUS_REJECTED = "Rejected"
+
+type RejectedError struct {
+	error
+}
+
+func NewRejectedError(err error) error {
+	return RejectedError{err}
+}
 
 func submit(args []string, lang, modelName string) {
 	if options.DryRun {
@@ -34,51 +58,78 @@ func submit(args []string, lang, modelName string) {
 		return
 	}
 
+	key := resultKey(modelName)
+	sanitizerChain := sanitizersFor(lang)
+	if err := leetgptsolver.ValidateSanitizers(sanitizerChain); err != nil {
+		log.Err(err).Msg("invalid sanitizers config")
+		return
+	}
+	if !slices.Contains([]string{ON_REJECTION_NONE, ON_REJECTION_STRIP, ON_REJECTION_NEUTRAL}, options.OnRejection) {
+		log.Error().Msgf("invalid on_rejection: %s", options.OnRejection)
+		return
+	}
 	log.Info().Msgf("Submitting %d solutions...", len(files))
-	submittedCnt := 0
-	acceptedCnt := 0
-	notAcceptedCnt := 0
-	skippedCnt := 0
-	errorsCnt := 0
-	leetcodeLimiter = rate.NewLimiter(rate.Limit(options.SubmitRateLimit), options.SubmitRateBurst)
+	tracker := newProgress("submit", len(files))
+	journal := openJournal("submit")
 outerLoop:
 	for i, file := range files {
 		log.Info().Msgf("[%d/%d] Submitting problem %s ...", i+1, len(files), file)
+		// counts the outcome and journals it, msg is the reason of skipping or the leetcode status
+		done := func(outcome, msg, failureClass string, err error) {
+			tracker.add(key, outcome)
+			event := JournalEvent{Event: JOURNAL_SUBMIT, Problem: file, Model: key, Lang: lang, Status: outcome, StatusMsg: msg, ErrorClass: failureClass}
+			journal.record(errorEvent(event, err))
+		}
 
 		var problem Problem
 		err := problem.ReadProblem(file)
 		if err != nil {
 			log.Err(err).Msg("Failed to read problem")
-			errorsCnt += 1
+			done(OUTCOME_ERROR, "", "", err)
 			continue
 		}
 
-		solv, ok := problem.GetSolution(modelName, lang)
+		solv, ok := problem.GetSolution(key, lang)
 		if !ok {
-			log.Warn().Msgf("Model %s has no solution in %s to submit", modelName, lang)
-			skippedCnt += 1
+			log.Warn().Msgf("Model %s has no solution in %s to submit", key, lang)
+			done(OUTCOME_SKIPPED, "no solution", "", nil)
 			continue
 		}
 		if solv.TypedCode == "" {
-			log.Error().Msgf("Model %s has empty solution", modelName)
-			skippedCnt += 1
+			log.Error().Msgf("Model %s has empty solution", key)
+			done(OUTCOME_SKIPPED, "empty solution", FAILURE_EXTRACTION, nil)
 			continue
 		}
-		subm, ok := problem.GetSubmission(modelName, lang)
+		if solv.LocalCheck != nil && !solv.LocalCheck.Passed && options.SkipFailedCheck {
+			log.Warn().Msgf("Skipping %s's solution: local check with %s failed", key, solv.LocalCheck.Tool)
+			done(OUTCOME_SKIPPED, "local check failed", "", nil)
+			continue
+		}
+		if site := problem.site(); site != leetcodeSite {
+			log.Error().Msgf("Problem is from %s, but submitting to %s. Use --site %s", site.Name, leetcodeSite.Name, site.Name)
+			done(OUTCOME_SKIPPED, "problem is from "+site.Name, "", nil)
+			continue
+		}
+		subm, ok := problem.GetSubmission(key, lang)
 		if !options.Force && (ok && subm.CheckResponse.Finished) {
-			log.Info().Msgf("%s's solution is already submitted", modelName)
-			skippedCnt += 1
+			log.Info().Msgf("%s's solution is already submitted", key)
+			done(OUTCOME_SKIPPED, "already submitted", "", nil)
 			continue
 		}
-		log.Info().Msgf("Submitting %s's solution...", modelName)
+		log.Info().Msgf("Submitting %s's solution...", key)
 		submission, err := submitAndCheckSolution(problem.Question, solv)
 		if err != nil {
-			errorsCnt += 1
+			done(OUTCOME_ERROR, "", "", err)
 			if errors.Is(err, ErrFatal) {
 				log.Err(err).Msgf("Aborting...")
+				// the remaining problems are not submitted, they are counted and journaled as canceled
+				for _, rest := range files[i+1:] {
+					tracker.add(key, OUTCOME_CANCELED)
+					journal.record(JournalEvent{Event: JOURNAL_SUBMIT, Problem: rest, Model: key, Lang: lang, Status: OUTCOME_CANCELED, StatusMsg: "aborted after a fatal error"})
+				}
 				break outerLoop
 			}
-			log.Err(err).Msgf("Failed to submit or check %s's solution", modelName)
+			log.Err(err).Msgf("Failed to submit or check %s's solution", key)
 			continue
 		}
 
@@ -86,71 +137,124 @@ outerLoop:
 		if problem.SubmissionsV2 == nil {
 			problem.SubmissionsV2 = map[string]map[string]Submission{}
 		}
-		if _, ok := problem.SubmissionsV2[modelName]; !ok {
-			problem.SubmissionsV2[modelName] = map[string]Submission{}
+		if _, ok := problem.SubmissionsV2[key]; !ok {
+			problem.SubmissionsV2[key] = map[string]Submission{}
 		}
-		problem.SubmissionsV2[modelName][lang] = *submission
+		problem.SubmissionsV2[key][lang] = *submission
 		if !options.DryRun {
 			err = problem.SaveProblemInto(file)
 			if err != nil {
 				log.Err(err).Msg("Failed to save the submission result")
-				errorsCnt += 1
+				done(OUTCOME_ERROR, submission.CheckResponse.StatusMsg, "", err)
 				continue
 			}
 		}
-		submittedCnt += 1
-		if submission.CheckResponse.StatusMsg == "Accepted" {
-			acceptedCnt += 1
+		if submission.CheckResponse.StatusMsg == STATUS_ACCEPTED {
+			done(OUTCOME_ACCEPTED, submission.CheckResponse.StatusMsg, "", nil)
 		} else {
-			notAcceptedCnt += 1
+			done(OUTCOME_NOT_ACCEPTED, submission.CheckResponse.StatusMsg, classifyFailure(solv, *submission, true), nil)
 		}
 	}
+	summary := tracker.summary()
+	accepted, notAccepted := summary.Counts[OUTCOME_ACCEPTED], summary.Counts[OUTCOME_NOT_ACCEPTED]
 	log.Info().Msgf("Files processed: %d", len(files))
-	log.Info().Msgf("Skipped problems: %d", skippedCnt)
-	log.Info().Msgf("Problems submitted successfully: %d (accepted: %d, not accepted: %d, unknown: %d)", submittedCnt, acceptedCnt, notAcceptedCnt, submittedCnt-acceptedCnt-notAcceptedCnt)
-	log.Info().Msgf("Errors: %d", errorsCnt)
+	log.Info().Msgf("Skipped problems: %d", summary.Counts[OUTCOME_SKIPPED])
+	log.Info().Msgf("Problems submitted successfully: %d (accepted: %d, not accepted: %d)", accepted+notAccepted, accepted, notAccepted)
+	log.Info().Msgf("Errors: %d", summary.Counts[OUTCOME_ERROR])
+	tracker.finish()
+	journal.close(summary)
 }
 
 func submitAndCheckSolution(q Question, s Solution) (*Submission, error) {
-	typedCode, err := codeToSubmit(s, options.AddMetadataComment)
-	if err != nil {
-		return nil, err
-	}
-	subReq := SubmitRequest{
-		Lang:       s.Lang,
-		QuestionId: q.Data.Question.Id,
-		TypedCode:  typedCode,
+	variant := CODE_VARIANT_PLAIN
+	if options.AddMetadataComment {
+		variant = CODE_VARIANT_METADATA
 	}
+	rejected := []string{}
+	for {
+		typedCode, applied, err := codeToSubmit(q, s, variant)
+		if err != nil {
+			return nil, err
+		}
+		subReq := SubmitRequest{
+			Lang:       s.Lang,
+			QuestionId: q.Data.Question.Id,
+			TypedCode:  typedCode,
+		}
 
-	submissionId, err := submitCode(SubmitUrl(q), subReq)
-	if err != nil {
-		var subErr InvalidCodeError
-		if errors.As(err, &subErr) {
-			// non-retriable submission error, like "Your code is too long"
-			return &Submission{
-				SubmitRequest: subReq,
-				CheckResponse: CheckResponse{
-					StatusMsg: subErr.Error(),
-					Finished:  true,
-				},
-				SubmittedAt: time.Now(),
-			}, nil
+		submissionId, err := submitCode(leetcodeSite.SubmitUrl(q.Data.Question.TitleSlug), subReq)
+		if err != nil {
+			var subErr InvalidCodeError
+			if errors.As(err, &subErr) {
+				// non-retriable submission error, like "Your code is too long"
+				return &Submission{
+					SubmitRequest: subReq,
+					CheckResponse: CheckResponse{
+						StatusMsg: subErr.Error(),
+						Finished:  true,
+					},
+					SubmittedAt:      time.Now(),
+					Sanitizers:       applied,
+					CodeVariant:      variant,
+					RejectedVariants: rejected,
+				}, nil
+			}
+			var rejErr RejectedError
+			if errors.As(err, &rejErr) {
+				log.Warn().Msgf("Submission of %s code rejected: %v", variant, rejErr)
+				rejected = append(rejected, variant)
+				// leetcode rejects some code with the metadata comment, so it is retried once with another comment
+				if retry := retryVariant(variant); retry != "" && len(rejected) == 1 {
+					log.Info().Msgf("Retrying with %s code...", retry)
+					variant = retry
+					continue
+				}
+				return &Submission{
+					SubmitRequest: subReq,
+					CheckResponse: CheckResponse{
+						StatusCode: http.StatusForbidden,
+						StatusMsg:  STATUS_REJECTED,
+					},
+					SubmittedAt:      time.Now(),
+					Sanitizers:       applied,
+					CodeVariant:      variant,
+					RejectedVariants: rejected,
+				}, nil
+			}
+
+			return nil, err
 		}
 
-		return nil, err
-	}
+		checkResponse, err := checkStatus(leetcodeSite.SubmissionCheckUrl(submissionId))
+		if err != nil {
+			return nil, err
+		}
 
-	checkResponse, err := checkStatus(SubmissionCheckUrl(submissionId))
-	if err != nil {
-		return nil, err
+		return &Submission{
+			SubmitRequest:    subReq,
+			SubmissionId:     submissionId,
+			CheckResponse:    *checkResponse,
+			SubmittedAt:      time.Now(),
+			Sanitizers:       applied,
+			CodeVariant:      variant,
+			RejectedVariants: rejected,
+		}, nil
 	}
+}
 
-	return &Submission{
-		SubmitRequest: subReq,
-		SubmissionId:  submissionId,
-		CheckResponse: *checkResponse,
-		SubmittedAt:   time.Now(),
-	}, nil
+// retryVariant returns the code variant to submit after the variant was rejected, or "" to give up
+func retryVariant(variant string) string {
+	retry := ""
+	switch options.OnRejection {
+	case ON_REJECTION_STRIP:
+		retry = CODE_VARIANT_PLAIN
+	case ON_REJECTION_NEUTRAL:
+		retry = CODE_VARIANT_NEUTRAL
+	}
+	if retry == variant {
+		return ""
+	}
+	return retry
 }
 
 func submitCode(url string, subReq SubmitRequest) (uint64, error) {
@@ -168,13 +272,16 @@ func submitCode(url string, subReq SubmitRequest) (uint64, error) {
 	i := 0
 	for i < maxRetries {
 		i += 1
-		if err := leetcodeLimiter.Wait(context.Background()); err != nil {
-			return 0, err
-		}
-
 		var code int
-		respBody, code, err = makeAuthorizedHttpRequest("POST", url, &reqBody)
-		if code == http.StatusBadRequest || code == http.StatusForbidden || code == http.StatusTooManyRequests{
+		// the request is throttled by the shared leetcode limiter, which also backs off on 429
+		respBody, code, err = makeAuthorizedHttpRequest("POST", url, bytes.NewReader(reqBody.Bytes()))
+		if code == http.StatusForbidden {
+			err = forbiddenSubmissionError(respBody, err)
+			if !errors.Is(err, ErrChallenged) {
+				return 0, err
+			}
+		}
+		if code == http.StatusBadRequest {
 			err_message := string(respBody)
 			if len(err_message) > 80 {
 				err_message = err_message[:80] + "..."
@@ -218,15 +325,45 @@ func submitCode(url string, subReq SubmitRequest) (uint64, error) {
 	return uint64(submissionId), nil
 }
 
+// forbiddenSubmissionError tells apart reasons of 403 from the submit endpoint. Only a json response
+// means leetcode refused the code, cloudflare challenges are retried (the leetcode limiter already backs off)
+// and failed authentication stops the run
+func forbiddenSubmissionError(respBody []byte, err error) error {
+	err_message := string(respBody)
+	if len(err_message) > 80 {
+		err_message = err_message[:80] + "..."
+	}
+	err = fmt.Errorf("%w. See response for details: %s", err, err_message)
+	lower := bytes.ToLower(respBody)
+	authFailed := slices.ContainsFunc(forbiddenAuthMarkers, func(marker string) bool {
+		return bytes.Contains(lower, []byte(marker))
+	})
+	switch {
+	case authFailed:
+		return NewFatalError(fmt.Errorf("not authorized, the session may be expired: %w", err))
+	case strings.HasPrefix(http.DetectContentType(respBody), "text/html"):
+		return fmt.Errorf("%w: %w", ErrChallenged, err)
+	case !json.Valid(respBody):
+		return NewNonRetriableError(err)
+	}
+	return NewRejectedError(err)
+}
+
+var ErrChallenged = errors.New("challenged by cloudflare")
+
+// parts of 403 responses to requests with an expired session or a wrong csrf token
+var forbiddenAuthMarkers = []string{"csrf", "authentication credentials", "not authenticated", "not logged in"}
+
 func checkStatus(url string) (*CheckResponse, error) {
 	var checkResp *CheckResponse
 	maxRetries := options.CheckRetries
 	i := 0
 	for i < maxRetries {
-		i += 1
-		if err := leetcodeLimiter.Wait(context.Background()); err != nil {
-			return nil, err
+		if i > 0 {
+			// judging takes a while, the leetcode limiter may not slow the polling down (e.g. when unlimited)
+			time.Sleep(options.CheckInterval)
 		}
+		i += 1
 		log.Trace().Msgf("checking submission status (%d/%d)...", i, maxRetries)
 		respBody, code, err := makeAuthorizedHttpRequest("GET", url, bytes.NewReader([]byte{}))
 		if code == http.StatusBadRequest || code == 403 || code == 499 {
@@ -261,33 +398,49 @@ func checkStatus(url string) (*CheckResponse, error) {
 	return checkResp, nil
 }
 
-func codeToSubmit(s Solution, addMetadataComment bool) (string, error) {
-	code := s.TypedCode
-	if s.Lang == "rust" {
-		// some models generate "pub struct Solution;" which is excessive and causes compilation error
-		code = strings.ReplaceAll(code, "pub struct Solution;\n", "")
-		code = strings.ReplaceAll(code, "struct Solution;\n", "")
+// sanitizersFor returns the sanitizer chain for the language: the configured one or the default
+func sanitizersFor(lang string) []string {
+	if !options.Sanitize {
+		return nil
 	}
+	if chain, ok := options.Sanitizers[lang]; ok {
+		return chain
+	}
+	return leetgptsolver.DefaultSanitizers
+}
 
-	if !addMetadataComment {
-		return code, nil
+// codeToSubmit sanitizes the solution code and adds the metadata comment.
+// Returns the code and names of sanitizers which changed it
+func codeToSubmit(q Question, s Solution, variant string) (string, []string, error) {
+	code, applied, err := leetgptsolver.SanitizeCode(s.TypedCode, s.Lang, q.FindSnippet(s.Lang), sanitizersFor(s.Lang))
+	if err != nil {
+		return "", nil, NewFatalError(err)
+	}
+	for _, name := range applied {
+		log.Info().Msgf("Code sanitized: %s", name)
+	}
+
+	if variant == CODE_VARIANT_PLAIN {
+		return code, applied, nil
 	}
 
 	commentPrefix := ""
 	switch s.Lang {
-	case "python", "python3", "ruby", "elixir", "bash":
+	case "python", "python3", "pythondata", "ruby", "elixir", "bash":
 		commentPrefix = "#"
-	case "java", "csharp", "cpp", "javascript", "typescript", "swift", "go", "rust", "php":
+	case "java", "csharp", "c", "cpp", "javascript", "typescript", "swift", "go", "golang", "rust", "php", "kotlin", "scala", "dart":
 		commentPrefix = "//"
 	case "mysql", "mssql", "postgresql", "oraclesql":
 		commentPrefix = "--"
 	}
 
 	if commentPrefix == "" {
-		return "", fmt.Errorf("unsupported language for metadata comment: %s", s.Lang)
+		return "", nil, fmt.Errorf("unsupported language for metadata comment: %s", s.Lang)
 	}
 
-	comment := commentPrefix + " leetgptsolver submission\n" +
-		fmt.Sprintf(commentPrefix+" solution generated by model %s at %s \n", s.Model, s.SolvedAt)
-	return comment + code, nil
+	comment := commentPrefix + " leetgptsolver submission\n"
+	if variant == CODE_VARIANT_METADATA {
+		comment += fmt.Sprintf(commentPrefix+" solution generated by model %s at %s \n", s.Model, s.SolvedAt)
+	}
+	return comment + code, applied, nil
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testResponse struct {
+	code        int
+	contentType string
+	body        string
+}
+
+func TestSubmitCode(t *testing.T) {
+	challenge := testResponse{http.StatusForbidden, "text/html", "<!DOCTYPE html><html><head><title>Just a moment...</title></head></html>"}
+	accepted := testResponse{http.StatusOK, "application/json", `{"submission_id": 123}`}
+	tests := []struct {
+		name        string
+		responses   []testResponse
+		expectedId  uint64
+		expectedErr func(error) bool
+	}{
+		{
+			name:       "accepted",
+			responses:  []testResponse{accepted},
+			expectedId: 123,
+		},
+		{
+			name:       "cloudflare challenge is retried",
+			responses:  []testResponse{challenge, accepted},
+			expectedId: 123,
+		},
+		{
+			name:      "cloudflare challenge is not a rejection",
+			responses: []testResponse{challenge, challenge},
+			expectedErr: func(err error) bool {
+				return errors.Is(err, ErrChallenged) && !errors.As(err, &RejectedError{}) && !errors.Is(err, ErrFatal)
+			},
+		},
+		{
+			name:        "code rejected by leetcode",
+			responses:   []testResponse{{http.StatusForbidden, "application/json", `{"error": "Forbidden"}`}},
+			expectedErr: func(err error) bool { return errors.As(err, &RejectedError{}) },
+		},
+		{
+			name:      "wrong csrf token",
+			responses: []testResponse{{http.StatusForbidden, "text/html", "<html><body><h1>Forbidden (403)</h1><p>CSRF verification failed.</p></body></html>"}},
+			expectedErr: func(err error) bool {
+				return errors.Is(err, ErrFatal) && !errors.As(err, &RejectedError{})
+			},
+		},
+		{
+			name:      "expired session",
+			responses: []testResponse{{http.StatusForbidden, "application/json", `{"detail": "Authentication credentials were not provided."}`}},
+			expectedErr: func(err error) bool {
+				return errors.Is(err, ErrFatal) && !errors.As(err, &RejectedError{})
+			},
+		},
+		{
+			name:        "bad request",
+			responses:   []testResponse{{http.StatusBadRequest, "application/json", `{"error": "bad request"}`}},
+			expectedErr: func(err error) bool { return errors.Is(err, ErrNonRetriable) },
+		},
+	}
+
+	retries := options.SubmitRetries
+	t.Cleanup(func() { options.SubmitRetries = retries })
+	setupLeetcodeClient(t)
+	options.SubmitRetries = 2
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := test.responses[min(requests, len(test.responses)-1)]
+				requests += 1
+				w.Header().Set("Content-Type", resp.contentType)
+				w.WriteHeader(resp.code)
+				w.Write([]byte(resp.body))
+			}))
+			defer server.Close()
+
+			id, err := submitCode(server.URL+"/problems/two-sum/submit/", SubmitRequest{Lang: "python3", QuestionId: "1", TypedCode: "pass"})
+			if test.expectedErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expectedErr != nil && (err == nil || !test.expectedErr(err)) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id != test.expectedId {
+				t.Errorf("expected submission id: %d, got: %d", test.expectedId, id)
+			}
+		})
+	}
+}
+
+// setupLeetcodeClient makes leetcode requests go without cookies of the user. Test servers are not
+// on the leetcode host, so they are not rate limited. The client is restored after the test
+func setupLeetcodeClient(t *testing.T) {
+	oldJar, oldUrl, oldState := cookieJarCache, leetcodeUrl, options.LeetcodeLimiterState
+	t.Cleanup(func() { cookieJarCache, leetcodeUrl, options.LeetcodeLimiterState = oldJar, oldUrl, oldState })
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookieJarCache = jar
+	leetcodeUrl, _ = url.Parse("https://leetcode.com/")
+	options.LeetcodeLimiterState = filepath.Join(t.TempDir(), "limiter.json")
+}
+
+func TestCheckStatusWaitsBetweenChecks(t *testing.T) {
+	retries, interval := options.CheckRetries, options.CheckInterval
+	t.Cleanup(func() { options.CheckRetries, options.CheckInterval = retries, interval })
+	setupLeetcodeClient(t)
+	options.CheckRetries = 3
+	options.CheckInterval = 50 * time.Millisecond
+
+	checks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks += 1
+		w.Header().Set("Content-Type", "application/json")
+		if checks < 3 {
+			w.Write([]byte(`{"state": "PENDING"}`))
+			return
+		}
+		w.Write([]byte(`{"state": "SUCCESS", "finished": true, "status_msg": "Accepted"}`))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := checkStatus(server.URL + "/submissions/detail/1/check/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Finished || checks != 3 {
+		t.Errorf("expected finished after 3 checks, got finished: %v after %d", resp.Finished, checks)
+	}
+	if elapsed := time.Since(start); elapsed < 2*options.CheckInterval {
+		t.Errorf("expected checks at least %s apart, all took %s", options.CheckInterval, elapsed)
+	}
+}
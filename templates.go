@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	leetgptsolver "whisk/leetgptsolver/pkg"
+
+	"github.com/rs/zerolog/log"
+)
+
+const TEMPLATE_EXT = ".tmpl"
+
+// PromptTemplate is a prompt template, either named (a file in the templates dir) or from the config
+type PromptTemplate struct {
+	// empty for templates from the config
+	Name string
+	// file the template was read from, empty for templates from the config
+	Path string
+	Text string
+	// system message of templates from the config. Named templates define it with {{define "system"}}
+	System string
+}
+
+func (t PromptTemplate) Hash() string {
+	if t.System != "" {
+		return hashString(t.System + "\n" + t.Text)
+	}
+	return hashString(t.Text)
+}
+
+// IsGoTemplate tells if the template uses text/template syntax instead of {placeholders}
+func (t PromptTemplate) IsGoTemplate() bool {
+	return strings.Contains(t.Text, "{{")
+}
+
+// promptData is passed to text/template templates
+type promptData struct {
+	Language   string
+	Title      string
+	Question   string
+	Snippet    string
+	Schema     string
+	Difficulty string
+	Category   string
+	Tags       []string
+	Examples   []leetgptsolver.Example
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  func(sep string, s []string) string { return strings.Join(s, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+}
+
+// findPromptTemplate finds the template for the question category and programming language.
+// Named templates are looked up in the templates dir, the most specific file wins:
+// <name>.<category>.<lang>.tmpl, <name>.<lang>.tmpl, <name>.<category>.tmpl, <name>.tmpl.
+// Empty name means prompt_templates or prompt_template from the config
+func findPromptTemplate(name, category, lang string) (PromptTemplate, error) {
+	if name == "" {
+		if categoryPrompt, ok := options.PromptTemplates[category]; ok && categoryPrompt != "" {
+			log.Debug().Msgf("Using prompt template for %s category", category)
+			return PromptTemplate{Text: categoryPrompt, System: options.SystemPrompt}, nil
+		}
+		if options.PromptTemplate == "" {
+			return PromptTemplate{}, errors.New("prompt_template is not set")
+		}
+		return PromptTemplate{Text: options.PromptTemplate, System: options.SystemPrompt}, nil
+	}
+
+	candidates := []string{
+		name + "." + category + "." + lang + TEMPLATE_EXT,
+		name + "." + lang + TEMPLATE_EXT,
+		name + "." + category + TEMPLATE_EXT,
+		name + TEMPLATE_EXT,
+	}
+	for _, candidate := range candidates {
+		path := filepath.Join(options.TemplatesDir, candidate)
+		if ok, _ := fileExists(path); !ok {
+			continue
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return PromptTemplate{}, fmt.Errorf("failed to read template: %w", err)
+		}
+		log.Debug().Msgf("Using prompt template %s", path)
+		return PromptTemplate{Name: name, Path: path, Text: string(text)}, nil
+	}
+
+	return PromptTemplate{}, fmt.Errorf("template %s not found in %s", name, options.TemplatesDir)
+}
+
+// render renders the template into the user and system messages. text/template templates get promptData,
+// other templates get {language}, {question}, {snippet} and {schema} replaced
+func (t PromptTemplate) render(data promptData) (string, string, error) {
+	if t.IsGoTemplate() {
+		tmpl, err := template.New(t.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(t.Text)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to execute template: %w", err)
+		}
+		var system bytes.Buffer
+		if tmpl.Lookup("system") != nil {
+			err = tmpl.ExecuteTemplate(&system, "system", data)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to execute system template: %w", err)
+			}
+		}
+		return strings.TrimSpace(buf.String()) + "\n", strings.TrimSpace(system.String()), nil
+	}
+
+	prompt := t.Text
+	if replaceInplace(&prompt, "{language}", data.Language) == 0 {
+		return "", "", errors.New("no {language} in prompt_template")
+	}
+	if replaceInplace(&prompt, "{question}", data.Question) == 0 {
+		return "", "", errors.New("no {question} in prompt_template")
+	}
+	if replaceInplace(&prompt, "{snippet}", data.Snippet) == 0 {
+		return "", "", errors.New("no {snippet} in prompt_template")
+	}
+	// schema is optional: only database and pandas problems have it
+	if replaceInplace(&prompt, "{schema}", data.Schema) > 0 && data.Schema == "" {
+		log.Warn().Msgf("No schema found for %s, {schema} is left empty", data.Language)
+	}
+	system := strings.ReplaceAll(t.System, "{language}", data.Language)
+	return prompt, system, nil
+}
+
+func replaceInplace(s *string, old, new string) int {
+	cnt := strings.Count(*s, old)
+	*s = strings.ReplaceAll(*s, old, new)
+	return cnt
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// language of the original question content
+const ORIGINAL_CONTENT_LANG = "en"
+
+// language of leetcode.cn translations
+const CN_CONTENT_LANG = "zh"
+
+// extensions of translation files, looked up in this order
+var translationFileExts = []string{".html", ".md", ".txt"}
+
+// QuestionInLang returns the question with the content in the given natural language
+func (p Problem) QuestionInLang(contentLang string) (Question, error) {
+	q := p.Question
+	if contentLang == "" || contentLang == ORIGINAL_CONTENT_LANG {
+		return q, nil
+	}
+	content, ok := p.Translations[contentLang]
+	if !ok || content == "" {
+		return q, fmt.Errorf("no %s translation found", contentLang)
+	}
+	q.Data.Question.Content = content
+	return q, nil
+}
+
+// translate stores translations of question content into problems. Translations are taken
+// either from leetcode.cn or from files named after problem slugs in the source directory
+func translate(args []string, contentLang, source string) {
+	files, err := filenamesFromArgs(args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get files")
+		return
+	}
+	if contentLang == "" || contentLang == ORIGINAL_CONTENT_LANG {
+		log.Fatal().Msgf("Content language must be set and differ from %s", ORIGINAL_CONTENT_LANG)
+		return
+	}
+	fromCn := source == "leetcode.cn"
+	if fromCn {
+		if leetcodeSite.Name != "leetcode.cn" {
+			log.Fatal().Msg("Translations from leetcode.cn require --site leetcode.cn")
+			return
+		}
+		if contentLang != CN_CONTENT_LANG {
+			log.Fatal().Msgf("leetcode.cn has only %s translations", CN_CONTENT_LANG)
+			return
+		}
+	}
+
+	translatedCnt := 0
+	errorsCnt := 0
+	for i, file := range files {
+		log.Info().Msgf("[%d/%d] Translating problem %s ...", i+1, len(files), file)
+
+		var problem Problem
+		err := problem.ReadProblem(file)
+		if err != nil {
+			log.Err(err).Msg("Failed to read the problem")
+			errorsCnt += 1
+			continue
+		}
+		if _, ok := problem.Translations[contentLang]; ok && !options.Force {
+			log.Info().Msgf("Already translated into %s", contentLang)
+			continue
+		}
+
+		slug := problem.Question.Data.Question.TitleSlug
+		var content string
+		if fromCn {
+			content, err = LoadTranslatedContent(slug)
+		} else {
+			content, err = readTranslationFile(source, slug)
+		}
+		if err != nil {
+			log.Err(err).Msg("Failed to get the translation")
+			errorsCnt += 1
+			continue
+		}
+
+		if problem.Translations == nil {
+			problem.Translations = map[string]string{}
+		}
+		problem.Translations[contentLang] = content
+		if !options.DryRun {
+			err = problem.SaveProblemInto(file)
+			if err != nil {
+				log.Err(err).Msg("Failed to save the translation")
+				errorsCnt += 1
+				continue
+			}
+		}
+		translatedCnt += 1
+	}
+	log.Info().Msgf("Files processed: %d", len(files))
+	log.Info().Msgf("Problems translated: %d", translatedCnt)
+	log.Info().Msgf("Errors: %d", errorsCnt)
+}
+
+func readTranslationFile(dir, slug string) (string, error) {
+	for _, ext := range translationFileExts {
+		name := filepath.Join(dir, slug+ext)
+		if ok, _ := fileExists(name); !ok {
+			continue
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+	return "", fmt.Errorf("no translation file for %s in %s", slug, dir)
+}
+
+func TranslatedContentQuery(slug string) ([]byte, error) {
+	query := map[string]interface{}{
+		"query": `query questionTranslations($titleSlug: String!)
+		{
+			question(titleSlug: $titleSlug) {
+				translatedTitle
+				translatedContent
+			}
+		}`,
+		"variables": map[string]string{
+			"titleSlug": slug,
+		},
+		"operationName": "questionTranslations",
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling GraphQL: %w", err)
+	}
+	return queryBytes, nil
+}
+
+// LoadTranslatedContent loads the question content translated by leetcode.cn
+func LoadTranslatedContent(slug string) (string, error) {
+	queryBytes, err := TranslatedContentQuery(slug)
+	if err != nil {
+		return "", err
+	}
+	respBody, _, err := makeAuthorizedHttpRequest("POST", leetcodeGraphqlUrl.String(), bytes.NewReader(queryBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to get translated content: %w", err)
+	}
+	var resp struct {
+		Data struct {
+			Question struct {
+				TranslatedContent string
+			}
+		}
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if resp.Data.Question.TranslatedContent == "" {
+		return "", fmt.Errorf("no translated content for %s", slug)
+	}
+	return resp.Data.Question.TranslatedContent, nil
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -10,6 +13,15 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// lock files are held for milliseconds, so a lock file older than the stale age is left by a crashed
+// process. The stale age must be below the timeout, so waiters remove such files instead of failing
+const (
+	LOCK_FILE_TIMEOUT   = 30 * time.Second
+	LOCK_FILE_STALE_AGE = 5 * time.Second
 )
 
 var ErrNonRetriable = errors.New("non-retriable error")
@@ -30,6 +42,12 @@ func NewFatalError(err error) error {
 	return fmt.Errorf("%w: %w", ErrFatal, err)
 }
 
+// hashString returns a short hex hash, good enough to detect changes
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
 func humanizeTime(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -54,6 +72,38 @@ func fileExists(name string) (bool, error) {
 	}
 }
 
+// acquireLockFile creates a lock file exclusively, waiting while another process holds it or until ctx is done.
+// A lock file left by a crashed process is removed when it becomes stale.
+// The returned function releases the lock.
+func acquireLockFile(ctx context.Context, name string) (func(), error) {
+	deadline := time.Now().Add(LOCK_FILE_TIMEOUT)
+	for {
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > LOCK_FILE_STALE_AGE {
+			log.Warn().Msgf("removing stale lock file %s", name)
+			os.Remove(name)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock file %s", name)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
 // allFilesFromProblemsDir retrieves all JSON files from the problems directory.
 func allFilesFromProblemsDir() ([]string, error) {
 	fsys := os.DirFS(options.Dir)